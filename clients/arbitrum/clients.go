@@ -0,0 +1,129 @@
+package arbitrum
+
+import (
+	"context"
+	"crypto-trade-client/clients/ethereum"
+	ethclient "crypto-trade-client/clients/ethereum/client"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// NodeInterfaceAddress is the address of Arbitrum's virtual NodeInterface contract,
+// it is only reachable with eth_call and eth_estimateGas
+var NodeInterfaceAddress = common.HexToAddress("0x00000000000000000000000000000000000000C8")
+
+const nodeInterfaceABI = `[
+	{"inputs":[{"name":"to","type":"address"},{"name":"contractCreation","type":"bool"},{"name":"data","type":"bytes"}],"name":"gasEstimateComponents","outputs":[{"name":"gasEstimate","type":"uint64"},{"name":"gasEstimateForL1","type":"uint64"},{"name":"baseFee","type":"uint256"},{"name":"l1BaseFeeEstimate","type":"uint256"}],"stateMutability":"payable","type":"function"}
+]`
+
+// L1GasMarginPercent is the headroom added to the L1 component of the gas estimate. The L1 gas is the L1 fee
+// divided by the L2 base fee, it grows when the L1 base fee rises between the estimate and the inclusion.
+const L1GasMarginPercent = 25
+
+var nodeInterface = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(nodeInterfaceABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// GasEstimateComponents is the result of NodeInterface.gasEstimateComponents
+type GasEstimateComponents struct {
+	// GasEstimate is the total gas limit of the transaction, including GasEstimateForL1
+	GasEstimate uint64
+	// GasEstimateForL1 is the part of the gas paying for posting the calldata to L1
+	GasEstimateForL1 uint64
+	// BaseFee is the L2 base fee
+	BaseFee *big.Int
+	// L1BaseFeeEstimate is the L1 base fee estimated by the sequencer
+	L1BaseFeeEstimate *big.Int
+}
+
+// GasLimit returns the gas limit of the transaction: GasEstimate with L1GasMarginPercent more L1 gas
+func (c *GasEstimateComponents) GasLimit() uint64 {
+	margin := (c.GasEstimateForL1*L1GasMarginPercent + 99) / 100
+	return c.GasEstimate + margin
+}
+
+type ArbClient struct {
+	*ethclient.EthClient
+}
+
+// NewArbClient creates a new Arbitrum client with the given endpoint and private key
+func NewArbClient(endpoint string, privateKey string) (*ArbClient, error) {
+	return NewArbClientWithHTTPClient(endpoint, privateKey, nil)
+}
+
+// NewArbClientWithHTTPClient creates an Arbitrum client sending its requests with httpClient, see ethclient.NewEthClientWithHTTPClient
+func NewArbClientWithHTTPClient(endpoint string, privateKey string, httpClient *http.Client) (*ArbClient, error) {
+	client, err := ethclient.NewEthClientWithHTTPClient(endpoint, "arbitrum", privateKey, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	arbClient := &ArbClient{client}
+	// an ether transfer costs more than 21000 gas on arbitrum because of the L1 component
+	client.WithGasEstimator(arbClient.estimateGas)
+
+	return arbClient, nil
+}
+
+// EstimateGasComponents returns the gas limit of the call split into its L2 and L1 parts
+func (c *ArbClient) EstimateGasComponents(from common.Address, to common.Address, value *big.Int, data []byte) (*GasEstimateComponents, error) {
+	return c.EstimateGasComponentsContext(context.Background(), from, to, value, data)
+}
+
+// EstimateGasComponentsContext is EstimateGasComponents with a context
+func (c *ArbClient) EstimateGasComponentsContext(ctx context.Context, from common.Address, to common.Address, value *big.Int, data []byte) (*GasEstimateComponents, error) {
+	input, err := nodeInterface.Pack("gasEstimateComponents", to, false, data)
+	if err != nil {
+		return nil, err
+	}
+
+	opt := ethereum.CallOption{
+		From: from.Hex(),
+		To:   NodeInterfaceAddress.Hex(),
+		Data: hexutil.Encode(input),
+	}
+	if value != nil {
+		opt.Value = (*hexutil.Big)(value)
+	}
+
+	output, err := c.CallContext(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return unpackGasEstimateComponents(output)
+}
+
+func (c *ArbClient) estimateGas(ctx context.Context, from common.Address, to common.Address, value *big.Int, data []byte) (uint64, error) {
+	components, err := c.EstimateGasComponentsContext(ctx, from, to, value, data)
+	if err != nil {
+		return 0, err
+	}
+	return components.GasLimit(), nil
+}
+
+func unpackGasEstimateComponents(output []byte) (*GasEstimateComponents, error) {
+	values, err := nodeInterface.Unpack("gasEstimateComponents", output)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("unexpected gasEstimateComponents output count %d", len(values))
+	}
+
+	return &GasEstimateComponents{
+		GasEstimate:       values[0].(uint64),
+		GasEstimateForL1:  values[1].(uint64),
+		BaseFee:           values[2].(*big.Int),
+		L1BaseFeeEstimate: values[3].(*big.Int),
+	}, nil
+}
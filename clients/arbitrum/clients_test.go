@@ -0,0 +1,98 @@
+package arbitrum
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"crypto-trade-client/clients/ethereum"
+	"crypto-trade-client/common/web/fetch/fetchtest"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	endpointMainnet = "https://arb1.arbitrum.io/rpc"
+)
+
+func TestArbClient_GetLatestBlockHeight(t *testing.T) {
+	recorder := fetchtest.Replay(t)
+	Convey("Test GetLatestBlockHeight", t, func() {
+		endpoint := endpointMainnet
+		client, err := NewArbClientWithHTTPClient(endpoint, "", recorder.HTTPClient())
+		So(err, ShouldBeNil)
+
+		height, err := client.GetLatestBlockHeight()
+		So(err, ShouldBeNil)
+		So(height, ShouldBeGreaterThan, int64(0))
+	})
+}
+
+func TestUnpackGasEstimateComponents(t *testing.T) {
+	Convey("Test unpack gasEstimateComponents output", t, func() {
+		output, err := nodeInterface.Methods["gasEstimateComponents"].Outputs.Pack(
+			uint64(350000), uint64(320000), big.NewInt(10000000), big.NewInt(30000000000))
+		So(err, ShouldBeNil)
+
+		components, err := unpackGasEstimateComponents(output)
+		So(err, ShouldBeNil)
+		So(components.GasEstimate, ShouldEqual, uint64(350000))
+		So(components.GasEstimateForL1, ShouldEqual, uint64(320000))
+		So(components.BaseFee.Int64(), ShouldEqual, int64(10000000))
+		So(components.L1BaseFeeEstimate.Int64(), ShouldEqual, int64(30000000000))
+	})
+}
+
+func TestGasEstimateComponents_GasLimit(t *testing.T) {
+	Convey("Test gas limit with the L1 margin", t, func() {
+		components := &GasEstimateComponents{GasEstimate: 350000, GasEstimateForL1: 320000}
+		So(components.GasLimit(), ShouldEqual, uint64(350000+80000))
+
+		// the margin is rounded up
+		components = &GasEstimateComponents{GasEstimate: 21003, GasEstimateForL1: 3}
+		So(components.GasLimit(), ShouldEqual, uint64(21004))
+
+		components = &GasEstimateComponents{GasEstimate: 21000}
+		So(components.GasLimit(), ShouldEqual, uint64(21000))
+	})
+}
+
+func TestArbClient_EstimateGasComponents(t *testing.T) {
+	Convey("Test gasEstimateComponents with a value above 2^64 wei", t, func() {
+		output, err := nodeInterface.Methods["gasEstimateComponents"].Outputs.Pack(
+			uint64(350000), uint64(320000), big.NewInt(10000000), big.NewInt(30000000000))
+		So(err, ShouldBeNil)
+
+		var call ethereum.CallOption
+		node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				ID     json.RawMessage   `json:"id"`
+				Method string            `json:"method"`
+				Params []json.RawMessage `json:"params"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			var result interface{} = map[string]string{"number": "0x1", "hash": common.Hash{1}.Hex()}
+			if req.Method == "eth_call" {
+				_ = json.Unmarshal(req.Params[0], &call)
+				result = hexutil.Encode(output)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		}))
+		defer node.Close()
+
+		client, err := NewArbClient(node.URL, "")
+		So(err, ShouldBeNil)
+
+		value, _ := new(big.Int).SetString("100000000000000000000", 10) // 100 ETH
+		to := common.HexToAddress("0xebdBa70B23edf9A69B7872b5Ff9A6Ba55e2F2FE4")
+		components, err := client.EstimateGasComponents(to, to, value, nil)
+		So(err, ShouldBeNil)
+		So(components.GasEstimate, ShouldEqual, uint64(350000))
+		So(call.To, ShouldEqual, NodeInterfaceAddress.Hex())
+		So(call.Value.ToInt().String(), ShouldEqual, value.String())
+	})
+}
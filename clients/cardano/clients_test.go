@@ -7,6 +7,9 @@ import (
 )
 
 func TestCardanoClient_GetBlock(t *testing.T) {
+	if testing.Short() {
+		t.Skip("calls the Tatum gateway with its own http client")
+	}
 	_, err := NewCardanoClient(context.Background(), hclog.NewNullLogger())
 	if err != nil {
 		t.Fatal(err)
@@ -2,6 +2,7 @@ package core
 
 import (
 	ethclient "crypto-trade-client/clients/ethereum/client"
+	"net/http"
 )
 
 type CoreClient struct {
@@ -10,7 +11,12 @@ type CoreClient struct {
 
 // NewCoreClient creates a new Core client with the given endpoint and private key
 func NewCoreClient(endpoint string, privateKey string) (*CoreClient, error) {
-	client, err := ethclient.NewEthClient(endpoint, "core", privateKey)
+	return NewCoreClientWithHTTPClient(endpoint, privateKey, nil)
+}
+
+// NewCoreClientWithHTTPClient creates a Core client sending its requests with httpClient, see ethclient.NewEthClientWithHTTPClient
+func NewCoreClientWithHTTPClient(endpoint string, privateKey string, httpClient *http.Client) (*CoreClient, error) {
+	client, err := ethclient.NewEthClientWithHTTPClient(endpoint, "core", privateKey, httpClient)
 	if err != nil {
 		return nil, err
 	}
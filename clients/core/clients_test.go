@@ -1,6 +1,7 @@
 package core
 
 import (
+	"crypto-trade-client/common/web/fetch/fetchtest"
 	. "github.com/smartystreets/goconvey/convey"
 	"testing"
 )
@@ -10,9 +11,10 @@ var (
 )
 
 func TestCoreClient_GetBlock(t *testing.T) {
+	recorder := fetchtest.Replay(t)
 	Convey("Test GetBlock", t, func() {
 		endpoint := endpointMainnet
-		client, err := NewCoreClient(endpoint, "")
+		client, err := NewCoreClientWithHTTPClient(endpoint, "", recorder.HTTPClient())
 		So(err, ShouldBeNil)
 
 		blockHeight := 16364779
@@ -23,9 +25,10 @@ func TestCoreClient_GetBlock(t *testing.T) {
 }
 
 func TestCoreClient_GetLatestBlockHeight(t *testing.T) {
+	recorder := fetchtest.Replay(t)
 	Convey("Test GetLatestBlockHeight", t, func() {
 		endpoint := endpointMainnet
-		client, err := NewCoreClient(endpoint, "")
+		client, err := NewCoreClientWithHTTPClient(endpoint, "", recorder.HTTPClient())
 		So(err, ShouldBeNil)
 
 		height, err := client.GetLatestBlockHeight()
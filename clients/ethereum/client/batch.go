@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"crypto-trade-client/clients/ethereum"
+	"crypto-trade-client/common/rpc"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultMaxBatchSize is the number of calls sent in one batch request, most providers reject larger batches
+const DefaultMaxBatchSize = 100
+
+// WithMaxBatchSize sets the number of calls sent in one batch request by the batch lookups
+func (ec *EthClient) WithMaxBatchSize(size int) *EthClient {
+	ec.maxBatchSize = size
+	return ec
+}
+
+// GetTransactionReceipts fetches the receipts of the transactions with batch requests.
+// The receipts are in the order of hashes, nil for a transaction which is not mined yet.
+func (ec *EthClient) GetTransactionReceipts(hashes []common.Hash) ([]*ethereum.Receipt, error) {
+	receipts := make([]ethereum.Receipt, len(hashes))
+	err := ec.batch(context.Background(), len(hashes), func(b *rpc.Batch, i int) {
+		b.Queue("GetTransactionReceipt", &receipts[i], hashes[i].Hex())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ethereum.Receipt, len(hashes))
+	for i := range receipts {
+		if receipts[i].TransactionHash != "" && receipts[i].BlockHash != "" {
+			result[i] = &receipts[i]
+		}
+	}
+	return result, nil
+}
+
+// GetBlocksByNumber fetches the blocks at the heights with batch requests.
+// The blocks are in the order of heights, nil for a height above the chain head.
+func (ec *EthClient) GetBlocksByNumber(heights []int64, fullTx bool) ([]*ethereum.Block, error) {
+	return ec.getBlocksByNumber(context.Background(), heights, fullTx)
+}
+
+func (ec *EthClient) getBlocksByNumber(ctx context.Context, heights []int64, fullTx bool) ([]*ethereum.Block, error) {
+	blocks := make([]ethereum.Block, len(heights))
+	err := ec.batch(ctx, len(heights), func(b *rpc.Batch, i int) {
+		b.Queue("GetBlockByNumber", &blocks[i], ethereum.EthBlockNumArg(heights[i]), fullTx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ethereum.Block, len(heights))
+	for i := range blocks {
+		if blocks[i].Hash != "" {
+			result[i] = &blocks[i]
+		}
+	}
+	return result, nil
+}
+
+// batch sends n calls queued by queue in batches of maxBatchSize, it fails on the first failed call
+func (ec *EthClient) batch(ctx context.Context, n int, queue func(b *rpc.Batch, i int)) error {
+	size := ec.maxBatchSize
+	if size <= 0 {
+		size = DefaultMaxBatchSize
+	}
+
+	for start := 0; start < n; start += size {
+		b := ec.ethRpc.NewBatch()
+		end := start + size
+		if end > n {
+			end = n
+		}
+		for i := start; i < end; i++ {
+			queue(b, i)
+		}
+
+		if err := b.Send(ctx); err != nil {
+			return err
+		}
+		for i, call := range b.Calls() {
+			if call.Error != nil {
+				return fmt.Errorf("%s of batch item %d: %w", call.Method, start+i, call.Error)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEthClient_GetTransactionReceipts(t *testing.T) {
+	node := newFakeNode(t)
+	height := node.mine()
+
+	hashes := make([]common.Hash, 5)
+	for i := range hashes {
+		hashes[i] = crypto.Keccak256Hash([]byte{byte(i)})
+		if i == 3 {
+			// not mined yet
+			continue
+		}
+		receipt := testReceipt(node, height, 1)
+		receipt["transactionHash"] = hashes[i].Hex()
+		node.setReceipt(hashes[i].Hex(), receipt)
+	}
+
+	client := node.newClient(t, "").WithMaxBatchSize(2)
+	receipts, err := client.GetTransactionReceipts(hashes)
+	require.NoError(t, err)
+	require.Len(t, receipts, len(hashes))
+	for i, receipt := range receipts {
+		if i == 3 {
+			assert.Nil(t, receipt)
+			continue
+		}
+		require.NotNil(t, receipt)
+		assert.Equal(t, hashes[i].Hex(), receipt.TransactionHash)
+	}
+	assert.Equal(t, 3, node.batches)
+}
+
+func TestEthClient_GetBlocksByNumber(t *testing.T) {
+	node := newFakeNode(t)
+	for i := 0; i < 3; i++ {
+		node.mine()
+	}
+
+	client := node.newClient(t, "")
+	blocks, err := client.GetBlocksByNumber([]int64{1, 2, 3, 4}, false)
+	require.NoError(t, err)
+	require.Len(t, blocks, 4)
+	for i, block := range blocks[:3] {
+		require.NotNil(t, block)
+		assert.Equal(t, hexutil.Uint64(i+1), block.Number)
+		assert.Equal(t, node.blocks[uint64(i+1)], block.Hash)
+	}
+	assert.Nil(t, blocks[3])
+	assert.Equal(t, 1, node.batches)
+}
+
+func TestEthClient_GetBlocksByNumber_ItemError(t *testing.T) {
+	node := newFakeNode(t)
+	node.mine()
+	node.handle("eth_getBlockByNumber", func(params []json.RawMessage) (interface{}, error) {
+		return nil, errors.New("header not found")
+	})
+
+	_, err := node.newClient(t, "").GetBlocksByNumber([]int64{1}, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "header not found")
+}
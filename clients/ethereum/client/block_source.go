@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"crypto-trade-client/clients/ethereum"
+	"crypto-trade-client/common/scanner"
+	"crypto-trade-client/common/stringutil"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BlockSource is the scanner.BlockSource of EVM chains, the blocks carry their full transactions
+type BlockSource struct {
+	client *EthClient
+}
+
+// NewBlockSource creates the block source of the client's chain
+func NewBlockSource(client *EthClient) *BlockSource {
+	return &BlockSource{client: client}
+}
+
+func (s *BlockSource) LatestHeight(ctx context.Context) (int64, error) {
+	return s.client.latestBlockHeight(ctx)
+}
+
+func (s *BlockSource) BlockByHeight(ctx context.Context, height int64) (*scanner.Block, error) {
+	block, err := s.client.ethRpc.GetBlockByNumber(ctx, ethereum.EthBlockNumArg(height), true)
+	if err != nil {
+		return nil, err
+	}
+	if stringutil.IsBlank(block.Hash) {
+		// a null result, the block is not produced yet
+		return nil, scanner.ErrBlockNotFound
+	}
+	return newScannerBlock(&block), nil
+}
+
+// FinalizedHeight returns the height of the finalized block, it fails on chains without finality
+func (s *BlockSource) FinalizedHeight(ctx context.Context) (int64, error) {
+	block, err := s.client.ethRpc.GetBlockByNumber(ctx, ethereum.Finalized, false)
+	if err != nil {
+		return 0, err
+	}
+	if stringutil.IsBlank(block.Hash) {
+		return 0, errors.New("no finalized block")
+	}
+	return int64(block.Number), nil
+}
+
+func newScannerBlock(block *ethereum.Block) *scanner.Block {
+	b := &scanner.Block{
+		Height:       int64(block.Number),
+		Hash:         block.Hash,
+		ParentHash:   block.ParentHash,
+		Time:         time.Unix(int64(block.Time), 0),
+		Transactions: make([]*scanner.Transaction, len(block.Transactions)),
+		Raw:          block,
+	}
+	for i, tx := range block.Transactions {
+		b.Transactions[i] = &scanner.Transaction{Hash: tx.Hash, Raw: tx}
+	}
+	return b
+}
+
+// BlocksByHeight fetches the blocks with batch requests of the client's max batch size
+func (s *BlockSource) BlocksByHeight(ctx context.Context, heights []int64) ([]*scanner.Block, error) {
+	blocks, err := s.client.getBlocksByNumber(ctx, heights, true)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*scanner.Block, len(blocks))
+	for i, block := range blocks {
+		if block == nil {
+			return nil, fmt.Errorf("block %d: %w", heights[i], scanner.ErrBlockNotFound)
+		}
+		result[i] = newScannerBlock(block)
+	}
+	return result, nil
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"crypto-trade-client/common/scanner"
+	"crypto-trade-client/common/web"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_BlockSource(t *testing.T) {
+	node := newFakeNode(t)
+	for i := 0; i < 3; i++ {
+		node.mine()
+	}
+	source := NewBlockSource(node.newClient(t, ""))
+
+	head, err := source.LatestHeight(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, head)
+
+	block, err := source.BlockByHeight(context.Background(), 2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, block.Height)
+	assert.Equal(t, blockHash(2, ""), block.Hash)
+	assert.Equal(t, blockHash(1, ""), block.ParentHash)
+	assert.Equal(t, time.Unix(1700000004, 0), block.Time)
+	assert.Empty(t, block.Transactions)
+
+	_, err = source.BlockByHeight(context.Background(), 4)
+	assert.ErrorIs(t, err, scanner.ErrBlockNotFound)
+}
+
+func Test_BlockSource_FinalizedHeight(t *testing.T) {
+	node := newFakeNode(t)
+	for i := 0; i < 5; i++ {
+		node.mine()
+	}
+	source := NewBlockSource(node.newClient(t, ""))
+
+	_, err := source.FinalizedHeight(context.Background())
+	assert.Error(t, err)
+
+	node.finalized = 3
+	height, err := source.FinalizedHeight(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, height)
+}
+
+func Test_BlockSource_BlocksByHeight(t *testing.T) {
+	node := newFakeNode(t)
+	for i := 0; i < 3; i++ {
+		node.mine()
+	}
+	source := NewBlockSource(node.newClient(t, ""))
+
+	blocks, err := source.BlocksByHeight(context.Background(), []int64{1, 2, 3})
+	require.NoError(t, err)
+	require.Len(t, blocks, 3)
+	for i, block := range blocks {
+		assert.EqualValues(t, i+1, block.Height)
+		assert.Equal(t, blockHash(uint64(i+1), ""), block.Hash)
+	}
+	assert.Equal(t, 1, node.batches)
+
+	_, err = source.BlocksByHeight(context.Background(), []int64{3, 4})
+	assert.ErrorIs(t, err, scanner.ErrBlockNotFound)
+}
+
+func Test_BlockSource_Context(t *testing.T) {
+	node := newFakeNode(t)
+	for i := 0; i < 3; i++ {
+		node.mine()
+	}
+	node.finalized = 2
+	source := NewBlockSource(node.newClient(t, ""))
+
+	// the calls of the scanner carry its trace id
+	ctx := web.WithTraceID(context.Background(), "scan-1")
+	_, err := source.LatestHeight(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "scan-1", node.traceOf("eth_getBlockByNumber"))
+	_, err = source.BlocksByHeight(web.WithTraceID(context.Background(), "scan-2"), []int64{1, 2})
+	require.NoError(t, err)
+	assert.Equal(t, "scan-2", node.traceOf("eth_getBlockByNumber"))
+
+	// cancelling the scanner aborts its calls
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = source.LatestHeight(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = source.BlockByHeight(ctx, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = source.FinalizedHeight(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = source.BlocksByHeight(ctx, []int64{1, 2})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -5,80 +5,109 @@ import (
 	"crypto-trade-client/clients/ethereum"
 	"crypto-trade-client/common/rpc"
 	"crypto-trade-client/common/stringutil"
-	"crypto/ecdsa"
+	"crypto-trade-client/common/web"
+	"crypto-trade-client/common/web/fetch"
 	"errors"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/hashicorp/go-hclog"
 	"math/big"
+	"net/http"
+	"time"
 )
 
-type EvmSigner struct {
-	PrivateKey    *ecdsa.PrivateKey
-	PublicAddress common.Address
-}
-
 type EthClient struct {
 	ethRpc *ethereum.EthRpc
 
 	ethClient *ethclient.Client
 
-	//privateKey *ecdsa.PrivateKey
-	signer *EvmSigner
+	signer TxSigner
+
+	nonces *NonceManager
+
+	feeMode   FeeMode
+	maxFeeCap *big.Int // only used by DynamicFee, nil means no cap
+
+	receiptPollInterval time.Duration
+	gasEstimator        GasEstimator // nil uses 21000 for ether transfers and eth_estimateGas for contract calls
+
+	maxBatchSize int // calls per batch request, 0 uses DefaultMaxBatchSize
+
+	ethSub   *ethereum.EthSubscription // set by DialSubscriptions
+	closeSub rpc.ClientCloser
 }
 
 // NewEthClient creates a new Ethereum client with the given endpoint, chain name, and private key
 func NewEthClient(endpoint string, chainName string, privateKeyHex string) (*EthClient, error) {
-	var eRPC ethereum.EthRpc
-	err := rpc.NewClient(context.Background(), endpoint, chainName, &eRPC, map[string]string{})
-	if err != nil {
-		return nil, err
+	return NewEthClientWithHTTPClient(endpoint, chainName, privateKeyHex, nil)
+}
+
+// NewEthClientWithHTTPClient creates a client like NewEthClient whose requests are sent by httpClient,
+// e.g. the one of a fetch.Recorder. A nil httpClient uses a default http.Client.
+func NewEthClientWithHTTPClient(endpoint string, chainName string, privateKeyHex string, httpClient *http.Client) (*EthClient, error) {
+	if stringutil.IsBlank(privateKeyHex) {
+		// no private key provided, so we can't sign transactions
+		return dialEthClient(endpoint, chainName, nil, httpClient)
 	}
 
-	ethClient, err := ethclient.Dial(endpoint)
+	signer, err := NewEvmSignerFromHex(privateKeyHex)
 	if err != nil {
 		return nil, err
 	}
 
-	if stringutil.IsBlank(privateKeyHex) {
-		// no private key provided, so we can't sign transactions
-		client := &EthClient{
-			ethRpc:    &eRPC,
-			ethClient: ethClient,
-			signer:    nil,
-		}
+	return dialEthClient(endpoint, chainName, signer, httpClient)
+}
 
-		return client, nil
+// NewEthClientWithSigner creates a new Ethereum client with the given endpoint, chain name, and signer,
+// e.g. loaded by LoadKeystoreSigner or a RemoteSigner. A nil signer creates a read-only client.
+func NewEthClientWithSigner(endpoint string, chainName string, signer TxSigner) (*EthClient, error) {
+	return dialEthClient(endpoint, chainName, signer, nil)
+}
+
+// dialEthClient creates the rpc and go-ethereum clients of the endpoint, both sending their requests with httpClient
+func dialEthClient(endpoint string, chainName string, signer TxSigner, httpClient *http.Client) (*EthClient, error) {
+	if httpClient == nil {
+		httpClient = &http.Client{}
 	}
 
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	var eRPC ethereum.EthRpc
+	fetchClient := fetch.NewClientWithCustomHttpClient(httpClient, endpoint, hclog.L().Named("jsonrpc2."+chainName))
+	err := rpc.NewClientWithCustomFetch(context.Background(), fetchClient, chainName, &eRPC)
 	if err != nil {
 		return nil, err
 	}
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, errors.New("error casting public key to ECDSA")
+	// go-ethereum's requests do not set the trace headers, fetch's do
+	gethHTTPClient := *httpClient
+	gethHTTPClient.Transport = web.NewTraceTransport(httpClient.Transport)
+	gethClient, err := gethrpc.DialOptions(context.Background(), endpoint, gethrpc.WithHTTPClient(&gethHTTPClient))
+	if err != nil {
+		return nil, err
 	}
 
-	signer := &EvmSigner{
-		PrivateKey:    privateKey,
-		PublicAddress: crypto.PubkeyToAddress(*publicKeyECDSA),
-	}
+	return newEthClient(&eRPC, ethclient.NewClient(gethClient), signer), nil
+}
 
+func newEthClient(eRPC *ethereum.EthRpc, ethClient *ethclient.Client, signer TxSigner) *EthClient {
 	client := &EthClient{
-		ethRpc:    &eRPC,
+		ethRpc:    eRPC,
 		ethClient: ethClient,
 		signer:    signer,
 	}
+	client.nonces = NewNonceManager(client.pendingTransactionCount)
+	return client
+}
 
-	return client, nil
+// WithSigner replaces the signer of the client
+func (ec *EthClient) WithSigner(signer TxSigner) *EthClient {
+	ec.signer = signer
+	return ec
 }
 
 func (ec *EthClient) GetBlock(hash string, height int64) (*ethereum.Block, error) {
-	bb, err := ec.bestBlockHeader()
+	bb, err := ec.bestBlockHeader(context.Background())
 	if err != nil {
 		return nil, err
 	}
@@ -99,7 +128,7 @@ func (ec *EthClient) GetBlock(hash string, height int64) (*ethereum.Block, error
 		if height > bb.Height {
 			return nil, ethereum.ErrBlockNotFound
 		}
-		block, err = ec.ethRpc.GetBlockByNumber(ethereum.EthBlockNumArg(height), true)
+		block, err = ec.ethRpc.GetBlockByNumber(context.Background(), ethereum.EthBlockNumArg(height), true)
 		if err != nil {
 			return nil, err
 		}
@@ -109,15 +138,19 @@ func (ec *EthClient) GetBlock(hash string, height int64) (*ethereum.Block, error
 }
 
 func (ec *EthClient) GetLatestBlockHeight() (int64, error) {
-	bh, err := ec.bestBlockHeader()
+	return ec.latestBlockHeight(context.Background())
+}
+
+func (ec *EthClient) latestBlockHeight(ctx context.Context) (int64, error) {
+	bh, err := ec.bestBlockHeader(ctx)
 	if err != nil {
 		return 0, err
 	}
 	return bh.Height, nil
 }
 
-func (ec *EthClient) bestBlockHeader() (ethereum.BlockHeader, error) {
-	block, err := ec.ethRpc.GetBlockByNumber(ethereum.Latest, false)
+func (ec *EthClient) bestBlockHeader(ctx context.Context) (ethereum.BlockHeader, error) {
+	block, err := ec.ethRpc.GetBlockByNumber(ctx, ethereum.Latest, false)
 	if err != nil {
 		return ethereum.BlockHeader{}, err
 	}
@@ -127,6 +160,9 @@ func (ec *EthClient) bestBlockHeader() (ethereum.BlockHeader, error) {
 		Height: int64(block.Number),
 		Time:   int64(block.Time),
 	}
+	if block.BaseFeePerGas != nil {
+		bh.BaseFeePerGas = block.BaseFeePerGas.ToInt().Int64()
+	}
 
 	return bh, nil
 }
@@ -140,61 +176,100 @@ func (ec *EthClient) PendingNonceAt(ctx context.Context, address common.Address)
 	return ec.ethClient.PendingNonceAt(ctx, address)
 }
 
+// pendingTransactionCount is the NonceFetcher of the client's NonceManager
+func (ec *EthClient) pendingTransactionCount(ctx context.Context, address common.Address) (uint64, error) {
+	count, err := ec.ethRpc.GetTransactionCount(ctx, address.Hex(), ethereum.Pending)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(count), nil
+}
+
+// Nonces returns the nonce manager used by the client to send transactions
+func (ec *EthClient) Nonces() *NonceManager {
+	return ec.nonces
+}
+
 func (ec *EthClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
 	return ec.ethClient.SuggestGasPrice(ctx)
 }
 
-func (ec *EthClient) _getSinnerPrivateKey(signer common.Address) (*ecdsa.PrivateKey, error) {
-	if ec.signer.PublicAddress != signer {
+// signerOf returns the client's signer if it signs for the address
+func (ec *EthClient) signerOf(address common.Address) (TxSigner, error) {
+	if ec.signer == nil {
+		return nil, errors.New("client has no signer")
+	}
+	if ec.signer.Address() != address {
 		return nil, errors.New("signer address does not match")
 	}
-	return ec.signer.PrivateKey, nil
+	return ec.signer, nil
 }
 
-// Transfer sends amount of ether or token to the given address
+// Transfer sends amount of ether to the given address
 func (ec *EthClient) Transfer(signer common.Address, to common.Address, value *big.Int) (common.Hash, error) {
-	ctx := context.Background()
+	return ec.TransferContext(context.Background(), signer, to, value)
+}
 
-	msgSignerPk, err := ec._getSinnerPrivateKey(signer)
+// TransferContext is Transfer with a context, the calls to the node carry its trace id, see web.WithTraceID
+func (ec *EthClient) TransferContext(ctx context.Context, signer common.Address, to common.Address, value *big.Int) (common.Hash, error) {
+	gasLimit, err := ec.transferGas(ctx, signer, to, value)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
-	// nonce
-	nonce, err := ec.PendingNonceAt(ctx, signer)
-	if err != nil {
-		return common.Hash{}, err
-	}
+	return ec.sendTx(ctx, signer, &to, value, gasLimit, nil)
+}
 
-	// gas limit
-	gasLimit := uint64(21000)
+// transferGas returns the gas limit of an ether transfer, 21000 unless the chain has a gas estimator
+func (ec *EthClient) transferGas(ctx context.Context, from common.Address, to common.Address, value *big.Int) (uint64, error) {
+	if ec.gasEstimator != nil {
+		return ec.gasEstimator(ctx, from, to, value, nil)
+	}
+	// gas limit of a plain ether transfer
+	return 21000, nil
+}
 
-	// gas price
-	gasPrice, err := ec.SuggestGasPrice(ctx)
+// sendTx signs the transaction with the client's signer and broadcasts it
+func (ec *EthClient) sendTx(ctx context.Context, signer common.Address, to *common.Address, value *big.Int, gasLimit uint64, data []byte) (common.Hash, error) {
+	txSigner, err := ec.signerOf(signer)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
-	chainID, err := ec.GetChainID(ctx)
+	// nonce, handed out locally so concurrent sends from the same address do not collide
+	nonce, generation, err := ec.nonces.Acquire(ctx, signer)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
-	signedTx, err := types.SignNewTx(msgSignerPk, types.NewEIP155Signer(chainID), &types.LegacyTx{
-		To:       &to,
-		Nonce:    nonce,
-		Value:    value,
-		Gas:      gasLimit,
-		GasPrice: gasPrice,
-	})
+	signedTx, err := ec.signTx(ctx, txSigner, nonce, to, value, gasLimit, data)
 	if err != nil {
+		// nothing was broadcast, so the nonce can be reused
+		ec.nonces.Release(signer, nonce, generation)
 		return common.Hash{}, err
 	}
 
 	err = ec.ethClient.SendTransaction(ctx, signedTx)
 	if err != nil {
+		// the node may or may not have accepted the nonce, resync it on the next send
+		ec.nonces.Reset(signer)
 		return common.Hash{}, err
 	}
 
 	return signedTx.Hash(), nil
 }
+
+func (ec *EthClient) signTx(ctx context.Context, txSigner TxSigner, nonce uint64, to *common.Address, value *big.Int, gasLimit uint64, data []byte) (*types.Transaction, error) {
+	chainID, err := ec.GetChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// gas price or base fee + tip, depends on the fee mode
+	txData, err := ec.newTxData(ctx, nonce, to, value, gasLimit, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return txSigner.SignTx(ctx, types.NewTx(txData), chainID)
+}
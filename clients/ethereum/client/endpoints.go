@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"crypto-trade-client/clients/ethereum"
+	"crypto-trade-client/common/config"
+	"crypto-trade-client/common/rpc"
+	"crypto-trade-client/common/web/fetch"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/hashicorp/go-hclog"
+)
+
+// BlockHeightCheck is a fetch.HealthCheck of EVM nodes, the height is the node's eth_blockNumber
+func BlockHeightCheck(ctx context.Context, client *fetch.Client) (uint64, error) {
+	resp, err := client.Post("").
+		WithContext(ctx).
+		SetJSONBody(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "eth_blockNumber", "params": []interface{}{}}).
+		Execute()
+	if err != nil {
+		return 0, err
+	}
+
+	var msg struct {
+		Result *hexutil.Uint64 `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err = json.Unmarshal(resp.BodyBytes(), &msg); err != nil {
+		return 0, fmt.Errorf("invalid eth_blockNumber response: %v", err)
+	}
+	if msg.Error != nil {
+		return 0, fmt.Errorf("eth_blockNumber failed, code %d: %s", msg.Error.Code, msg.Error.Message)
+	}
+	if msg.Result == nil {
+		return 0, errors.New("eth_blockNumber returned no result")
+	}
+	return uint64(*msg.Result), nil
+}
+
+// NewEthClientWithBalancer creates a client whose requests are spread over the endpoints of the balancer,
+// failing over to the other endpoints when one is down. The caller starts the health checks of the balancer.
+func NewEthClientWithBalancer(balancer *fetch.BalancedClient, chainName string, signer TxSigner) (*EthClient, error) {
+	var eRPC ethereum.EthRpc
+	err := rpc.NewClientWithCustomFetch(context.Background(), balancer, chainName, &eRPC)
+	if err != nil {
+		return nil, err
+	}
+
+	status := balancer.Status()
+	if len(status) == 0 {
+		return nil, fetch.ErrNoEndpoints
+	}
+	// the URL is only used to pick the HTTP transport, the balancer replaces it on every request
+	gethClient, err := gethrpc.DialOptions(context.Background(), status[0].URL, gethrpc.WithHTTPClient(balancer.HTTPClient()))
+	if err != nil {
+		return nil, err
+	}
+
+	return newEthClient(&eRPC, ethclient.NewClient(gethClient), signer), nil
+}
+
+// NewEthClientForEndpoints creates a client balanced over the endpoints, e.g. config.Chain.AllEndpoints,
+// checking their block heights until ctx is done so that lagging nodes are avoided
+func NewEthClientForEndpoints(ctx context.Context, endpoints []config.Endpoint, chainName string, signer TxSigner) (*EthClient, error) {
+	balancer := fetch.NewBalancedClient(endpoints, hclog.L().Named("jsonrpc2."+chainName)).
+		WithHealthCheck(BlockHeightCheck, fetch.DefaultHealthCheckInterval)
+
+	client, err := NewEthClientWithBalancer(balancer, chainName, signer)
+	if err != nil {
+		return nil, err
+	}
+	balancer.Start(ctx)
+	return client, nil
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"crypto-trade-client/common/config"
+	"crypto-trade-client/common/web/fetch"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_BlockHeightCheck(t *testing.T) {
+	node := newFakeNode(t)
+	for i := 0; i < 7; i++ {
+		node.mine()
+	}
+
+	height, err := BlockHeightCheck(context.Background(), fetch.NewClientWithEndpoint(node.URL(), hclog.NewNullLogger()))
+	require.NoError(t, err)
+	assert.EqualValues(t, 7, height)
+
+	node.handle("eth_blockNumber", func(params []json.RawMessage) (interface{}, error) {
+		return nil, errors.New("syncing")
+	})
+	_, err = BlockHeightCheck(context.Background(), fetch.NewClientWithEndpoint(node.URL(), hclog.NewNullLogger()))
+	assert.EqualError(t, err, "eth_blockNumber failed, code -32000: syncing")
+}
+
+func Test_EthClient_endpoints(t *testing.T) {
+	ahead, behind := newFakeNode(t), newFakeNode(t)
+	for i := 0; i < 20; i++ {
+		ahead.mine()
+	}
+	behind.mine()
+
+	var aheadCalls, behindCalls int32
+	ahead.onCall = func(method string) { atomic.AddInt32(&aheadCalls, 1) }
+	behind.onCall = func(method string) { atomic.AddInt32(&behindCalls, 1) }
+
+	balancer := fetch.NewBalancedClient([]config.Endpoint{{URL: behind.URL()}, {URL: ahead.URL()}}, hclog.NewNullLogger()).
+		WithHealthCheck(BlockHeightCheck, 0)
+	client, err := NewEthClientWithBalancer(balancer, "fake", nil)
+	require.NoError(t, err)
+
+	balancer.CheckHealth(context.Background())
+	assert.True(t, balancer.Status()[0].Lagging)
+	atomic.StoreInt32(&behindCalls, 0)
+
+	for i := 0; i < 5; i++ {
+		height, err := client.GetLatestBlockHeight()
+		require.NoError(t, err)
+		assert.EqualValues(t, 20, height)
+
+		chainID, err := client.GetChainID(context.Background())
+		require.NoError(t, err)
+		assert.EqualValues(t, 10, chainID.Int64())
+	}
+	assert.Zero(t, atomic.LoadInt32(&behindCalls))
+
+	// the lagging node is still better than none
+	ahead.server.Close()
+	height, err := client.GetLatestBlockHeight()
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, height)
+	_, err = client.GetChainID(context.Background())
+	require.NoError(t, err)
+}
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"crypto-trade-client/clients/ethereum"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"math/big"
+	"strings"
+)
+
+// erc20ABI contains the subset of the ERC-20 interface used by EthClient
+const erc20ABI = `[
+	{"constant":true,"inputs":[],"name":"symbol","outputs":[{"name":"","type":"string"}],"type":"function"},
+	{"constant":true,"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"type":"function"},
+	{"constant":true,"inputs":[{"name":"owner","type":"address"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"type":"function"},
+	{"constant":false,"inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"name":"transfer","outputs":[{"name":"","type":"bool"}],"type":"function"}
+]`
+
+var erc20 = mustParseABI(erc20ABI)
+
+func mustParseABI(def string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(def))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// TokenBalance returns the raw balance of owner in the ERC-20 token, it is not scaled by decimals
+func (ec *EthClient) TokenBalance(token common.Address, owner common.Address) (*big.Int, error) {
+	var balance *big.Int
+	if err := ec.callToken(token, &balance, "balanceOf", owner); err != nil {
+		return nil, err
+	}
+	return balance, nil
+}
+
+// TokenDecimals returns the decimals of the ERC-20 token
+func (ec *EthClient) TokenDecimals(token common.Address) (uint8, error) {
+	var decimals uint8
+	if err := ec.callToken(token, &decimals, "decimals"); err != nil {
+		return 0, err
+	}
+	return decimals, nil
+}
+
+// TokenSymbol returns the symbol of the ERC-20 token
+func (ec *EthClient) TokenSymbol(token common.Address) (string, error) {
+	var symbol string
+	if err := ec.callToken(token, &symbol, "symbol"); err != nil {
+		return "", err
+	}
+	return symbol, nil
+}
+
+// TransferToken sends amount of the ERC-20 token to the given address.
+// amount is the raw token amount, the caller scales it by TokenDecimals.
+func (ec *EthClient) TransferToken(signer common.Address, token common.Address, to common.Address, amount *big.Int) (common.Hash, error) {
+	return ec.TransferTokenContext(context.Background(), signer, token, to, amount)
+}
+
+// TransferTokenContext is TransferToken with a context, the calls to the node carry its trace id
+func (ec *EthClient) TransferTokenContext(ctx context.Context, signer common.Address, token common.Address, to common.Address, amount *big.Int) (common.Hash, error) {
+	data, err := erc20.Pack("transfer", to, amount)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	gasLimit, err := ec.estimateGas(ctx, signer, token, nil, data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return ec.sendTx(ctx, signer, &token, big.NewInt(0), gasLimit, data)
+}
+
+// GasEstimator estimates the gas limit of a transaction, it replaces eth_estimateGas on chains
+// whose gas has extra components, e.g. the L1 part of Arbitrum transactions
+type GasEstimator func(ctx context.Context, from common.Address, to common.Address, value *big.Int, data []byte) (uint64, error)
+
+// WithGasEstimator sets the estimator used for the gas limit of transactions built by the client
+func (ec *EthClient) WithGasEstimator(estimator GasEstimator) *EthClient {
+	ec.gasEstimator = estimator
+	return ec
+}
+
+func (ec *EthClient) estimateGas(ctx context.Context, from common.Address, to common.Address, value *big.Int, data []byte) (uint64, error) {
+	if ec.gasEstimator != nil {
+		return ec.gasEstimator(ctx, from, to, value, data)
+	}
+	return ec.EstimateGasContext(ctx, from, to, value, data)
+}
+
+// EstimateGas asks the node for the gas needed to execute the call from `from` to `to`
+func (ec *EthClient) EstimateGas(from common.Address, to common.Address, value *big.Int, data []byte) (uint64, error) {
+	return ec.EstimateGasContext(context.Background(), from, to, value, data)
+}
+
+// EstimateGasContext is EstimateGas with a context
+func (ec *EthClient) EstimateGasContext(ctx context.Context, from common.Address, to common.Address, value *big.Int, data []byte) (uint64, error) {
+	opt := ethereum.CallOption{
+		From: from.Hex(),
+		To:   to.Hex(),
+		Data: hexutil.Encode(data),
+	}
+	if value != nil {
+		opt.Value = (*hexutil.Big)(value)
+	}
+
+	gas, err := ec.ethRpc.EstimateGas(ctx, opt, ethereum.Latest)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(gas), nil
+}
+
+// Call executes eth_call against the latest block
+func (ec *EthClient) Call(opt ethereum.CallOption) ([]byte, error) {
+	return ec.CallContext(context.Background(), opt)
+}
+
+// CallContext is Call with a context
+func (ec *EthClient) CallContext(ctx context.Context, opt ethereum.CallOption) ([]byte, error) {
+	return ec.ethRpc.Call(ctx, opt, ethereum.Latest)
+}
+
+// callToken executes a read-only ERC-20 method with eth_call and unpacks the single return value into out
+func (ec *EthClient) callToken(token common.Address, out interface{}, method string, args ...interface{}) error {
+	data, err := erc20.Pack(method, args...)
+	if err != nil {
+		return err
+	}
+
+	result, err := ec.Call(ethereum.CallOption{
+		To:   token.Hex(),
+		Data: hexutil.Encode(data),
+	})
+	if err != nil {
+		return err
+	}
+	if len(result) == 0 {
+		return fmt.Errorf("empty result of %s, %s may not be an ERC-20 contract", method, token.Hex())
+	}
+
+	return unpackERC20(method, result, out)
+}
+
+func unpackERC20(method string, data []byte, out interface{}) error {
+	values, err := erc20.Unpack(method, data)
+	if err != nil {
+		return err
+	}
+	return erc20.Methods[method].Outputs.Copy(out, values)
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"crypto-trade-client/clients/ethereum"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestERC20_PackTransfer(t *testing.T) {
+	to := common.HexToAddress("0xebdBa70B23edf9A69B7872b5Ff9A6Ba55e2F2FE4")
+	data, err := erc20.Pack("transfer", to, big.NewInt(1000000))
+	require.NoError(t, err)
+
+	// transfer(address,uint256) selector followed by two 32 bytes words
+	assert.Equal(t, "a9059cbb", hex.EncodeToString(data[:4]))
+	assert.Len(t, data, 4+32+32)
+	assert.Equal(t, to.Bytes(), data[4+12:4+32])
+	assert.Equal(t, int64(1000000), new(big.Int).SetBytes(data[4+32:]).Int64())
+}
+
+func TestERC20_Unpack(t *testing.T) {
+	var balance *big.Int
+	err := unpackERC20("balanceOf", math.U256Bytes(big.NewInt(42)), &balance)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), balance.Int64())
+
+	var decimals uint8
+	err = unpackERC20("decimals", math.U256Bytes(big.NewInt(6)), &decimals)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(6), decimals)
+
+	encoded, err := erc20.Methods["symbol"].Outputs.Pack("USDT")
+	require.NoError(t, err)
+	var symbol string
+	err = unpackERC20("symbol", encoded, &symbol)
+	require.NoError(t, err)
+	assert.Equal(t, "USDT", symbol)
+}
+
+func TestEthClient_EstimateGas_LargeValue(t *testing.T) {
+	node := newFakeNode(t)
+	var value *hexutil.Big
+	node.handle("eth_estimateGas", func(params []json.RawMessage) (interface{}, error) {
+		var opt ethereum.CallOption
+		if err := json.Unmarshal(params[0], &opt); err != nil {
+			return nil, err
+		}
+		value = opt.Value
+		return hexutil.EncodeUint64(21000), nil
+	})
+
+	// 100 ether is above 2^64 wei
+	amount, _ := new(big.Int).SetString("100000000000000000000", 10)
+	from := common.HexToAddress("0xebdBa70B23edf9A69B7872b5Ff9A6Ba55e2F2FE4")
+	gas, err := node.newClient(t, "").EstimateGas(from, from, amount, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 21000, gas)
+	require.NotNil(t, value)
+	assert.Equal(t, amount, value.ToInt())
+}
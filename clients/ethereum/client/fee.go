@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"crypto-trade-client/clients/ethereum"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+)
+
+// FeeMode decides how EthClient prices the transactions it builds
+type FeeMode int
+
+const (
+	// LegacyFee builds a types.LegacyTx priced with eth_gasPrice
+	LegacyFee FeeMode = iota
+	// DynamicFee builds a types.DynamicFeeTx priced with the latest base fee plus eth_maxPriorityFeePerGas
+	DynamicFee
+)
+
+var (
+	// ErrMaxFeeBelowBaseFee is returned when the caller's max fee cap can not even cover the current base fee
+	ErrMaxFeeBelowBaseFee = errors.New("max fee cap is below the current base fee")
+)
+
+// WithDynamicFee switches the client to EIP-1559 transactions.
+// maxFeeCap is the highest fee per gas the caller is willing to pay, nil means no cap.
+func (ec *EthClient) WithDynamicFee(maxFeeCap *big.Int) *EthClient {
+	ec.feeMode = DynamicFee
+	ec.maxFeeCap = maxFeeCap
+	return ec
+}
+
+// WithLegacyFee switches the client back to legacy gas price transactions
+func (ec *EthClient) WithLegacyFee() *EthClient {
+	ec.feeMode = LegacyFee
+	ec.maxFeeCap = nil
+	return ec
+}
+
+// FeeMode returns the fee mode used to build transactions
+func (ec *EthClient) FeeMode() FeeMode {
+	return ec.feeMode
+}
+
+// SuggestGasTipCap returns the priority fee suggested by the node
+func (ec *EthClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	tip, err := ec.ethRpc.MaxPriorityFeePerGas(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tip == nil {
+		return big.NewInt(0), nil
+	}
+	return tip.ToInt(), nil
+}
+
+// latestBaseFee returns the base fee of the latest block, nil if the chain has no base fee
+func (ec *EthClient) latestBaseFee(ctx context.Context) (*big.Int, error) {
+	block, err := ec.ethRpc.GetBlockByNumber(ctx, ethereum.Latest, false)
+	if err != nil {
+		return nil, err
+	}
+	if block.BaseFeePerGas == nil {
+		return nil, nil
+	}
+	return block.BaseFeePerGas.ToInt(), nil
+}
+
+// newTxData builds the unsigned transaction with the fee fields priced according to the fee mode.
+// It falls back to a legacy transaction when the chain does not report a base fee.
+func (ec *EthClient) newTxData(ctx context.Context, nonce uint64, to *common.Address, value *big.Int, gasLimit uint64, data []byte) (types.TxData, error) {
+	if ec.feeMode == DynamicFee {
+		baseFee, err := ec.latestBaseFee(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if baseFee != nil {
+			tip, err := ec.SuggestGasTipCap(ctx)
+			if err != nil {
+				return nil, err
+			}
+			tipCap, feeCap, err := calcDynamicFee(baseFee, tip, ec.maxFeeCap)
+			if err != nil {
+				return nil, err
+			}
+			return &types.DynamicFeeTx{
+				Nonce:     nonce,
+				GasTipCap: tipCap,
+				GasFeeCap: feeCap,
+				Gas:       gasLimit,
+				To:        to,
+				Value:     value,
+				Data:      data,
+			}, nil
+		}
+	}
+
+	gasPrice, err := ec.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Gas:      gasLimit,
+		To:       to,
+		Value:    value,
+		Data:     data,
+	}, nil
+}
+
+// calcDynamicFee computes tip cap and fee cap the same way as go-ethereum's bind package:
+// fee cap = 2 * base fee + tip, so that the transaction stays valid for a few full blocks.
+// When maxFeeCap is given the fee cap is lowered to it, and the tip is lowered to the fee cap.
+func calcDynamicFee(baseFee, tip, maxFeeCap *big.Int) (*big.Int, *big.Int, error) {
+	tipCap := new(big.Int).Set(tip)
+	feeCap := new(big.Int).Add(tipCap, new(big.Int).Mul(baseFee, big.NewInt(2)))
+
+	if maxFeeCap != nil {
+		if maxFeeCap.Cmp(baseFee) < 0 {
+			return nil, nil, ErrMaxFeeBelowBaseFee
+		}
+		if feeCap.Cmp(maxFeeCap) > 0 {
+			feeCap = new(big.Int).Set(maxFeeCap)
+		}
+	}
+	if tipCap.Cmp(feeCap) > 0 {
+		tipCap = new(big.Int).Set(feeCap)
+	}
+
+	return tipCap, feeCap, nil
+}
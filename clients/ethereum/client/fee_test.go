@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalcDynamicFee(t *testing.T) {
+	gwei := func(v int64) *big.Int {
+		return new(big.Int).Mul(big.NewInt(v), big.NewInt(1e9))
+	}
+
+	tests := []struct {
+		name      string
+		baseFee   *big.Int
+		tip       *big.Int
+		maxFeeCap *big.Int
+		wantTip   *big.Int
+		wantFee   *big.Int
+		wantErr   error
+	}{
+		{
+			name:    "no cap uses twice the base fee plus tip",
+			baseFee: gwei(10),
+			tip:     gwei(2),
+			wantTip: gwei(2),
+			wantFee: gwei(22),
+		},
+		{
+			name:      "cap above computed fee is ignored",
+			baseFee:   gwei(10),
+			tip:       gwei(2),
+			maxFeeCap: gwei(30),
+			wantTip:   gwei(2),
+			wantFee:   gwei(22),
+		},
+		{
+			name:      "cap lowers the fee cap",
+			baseFee:   gwei(10),
+			tip:       gwei(2),
+			maxFeeCap: gwei(15),
+			wantTip:   gwei(2),
+			wantFee:   gwei(15),
+		},
+		{
+			name:      "tip is lowered to the fee cap",
+			baseFee:   gwei(10),
+			tip:       gwei(20),
+			maxFeeCap: gwei(12),
+			wantTip:   gwei(12),
+			wantFee:   gwei(12),
+		},
+		{
+			name:      "cap below base fee is rejected",
+			baseFee:   gwei(10),
+			tip:       gwei(2),
+			maxFeeCap: gwei(9),
+			wantErr:   ErrMaxFeeBelowBaseFee,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tipCap, feeCap, err := calcDynamicFee(tt.baseFee, tt.tip, tt.maxFeeCap)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, 0, tt.wantTip.Cmp(tipCap), "tip cap %v, want %v", tipCap, tt.wantTip)
+			assert.Equal(t, 0, tt.wantFee.Cmp(feeCap), "fee cap %v, want %v", feeCap, tt.wantFee)
+		})
+	}
+}
+
+func TestEthClient_FeeContext(t *testing.T) {
+	node := newFakeNode(t)
+	node.mine()
+	client := node.newClient(t, "")
+
+	tip, err := client.SuggestGasTipCap(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, node.tip, tip.Uint64())
+
+	// a cancelled context aborts the calls instead of reaching the node
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.SuggestGasTipCap(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = client.latestBaseFee(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,203 @@
+package client
+
+import (
+	"crypto-trade-client/common/config"
+	"crypto-trade-client/common/stringutil"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/term"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// DefaultDerivationPath is the BIP-44 path of the first Ethereum account
+const DefaultDerivationPath = "m/44'/60'/0'/0/0"
+
+// PasswordSource provides the password used to decrypt a keystore file
+type PasswordSource func() (string, error)
+
+// PasswordFromEnv reads the password from the environment variable
+func PasswordFromEnv(name string) PasswordSource {
+	return func() (string, error) {
+		password, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return password, nil
+	}
+}
+
+// PasswordFromFile reads the password from the first line of the file
+func PasswordFromFile(path string) PasswordSource {
+	return func() (string, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("error reading password file: %v", err)
+		}
+		return strings.TrimRight(strings.SplitN(string(data), "\n", 2)[0], "\r"), nil
+	}
+}
+
+// PasswordFromPrompt asks the password on the terminal without echoing it
+func PasswordFromPrompt(prompt string) PasswordSource {
+	return func() (string, error) {
+		fd := int(os.Stdin.Fd())
+		if !term.IsTerminal(fd) {
+			return "", errors.New("stdin is not a terminal, can not prompt for password")
+		}
+		_, _ = fmt.Fprint(os.Stderr, prompt)
+		password, err := term.ReadPassword(fd)
+		_, _ = fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return string(password), nil
+	}
+}
+
+// LoadKeystoreSigner decrypts a go-ethereum V3 keystore file
+func LoadKeystoreSigner(path string, password PasswordSource) (*EvmSigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading keystore file: %v", err)
+	}
+
+	pass, err := password()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := keystore.DecryptKey(data, pass)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting keystore: %v", err)
+	}
+
+	return NewEvmSigner(key.PrivateKey), nil
+}
+
+// LoadMnemonicSigner derives the signer from a BIP-39 mnemonic and an optional passphrase
+// following the BIP-32 derivation path, e.g. DefaultDerivationPath
+func LoadMnemonicSigner(mnemonic string, passphrase string, path string) (*EvmSigner, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mnemonic: %v", err)
+	}
+
+	if stringutil.IsBlank(path) {
+		path = DefaultDerivationPath
+	}
+	derivationPath, err := accounts.ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := deriveKey(seed, derivationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEvmSigner(privateKey), nil
+}
+
+// LoadSigner builds the in-process signer configured for the chain, in order of preference
+// keystore file, mnemonic file and plain private key.
+// It returns nil when the chain has no key configured.
+func LoadSigner(chain config.Chain) (TxSigner, error) {
+	var signer *EvmSigner
+	var err error
+
+	switch {
+	case !stringutil.IsBlank(chain.Keystore):
+		var password PasswordSource
+		switch {
+		case !stringutil.IsBlank(chain.PasswordEnv):
+			password = PasswordFromEnv(chain.PasswordEnv)
+		case !stringutil.IsBlank(chain.PasswordFile):
+			password = PasswordFromFile(chain.PasswordFile)
+		default:
+			password = PasswordFromPrompt(fmt.Sprintf("Password of %s keystore: ", chain.Name))
+		}
+		signer, err = LoadKeystoreSigner(chain.Keystore, password)
+	case !stringutil.IsBlank(chain.MnemonicFile):
+		data, rerr := os.ReadFile(chain.MnemonicFile)
+		if rerr != nil {
+			return nil, fmt.Errorf("error reading mnemonic file: %v", rerr)
+		}
+		passphrase := ""
+		if !stringutil.IsBlank(chain.PasswordEnv) {
+			passphrase = os.Getenv(chain.PasswordEnv)
+		}
+		signer, err = LoadMnemonicSigner(string(data), passphrase, chain.DerivationPath)
+	case !stringutil.IsBlank(chain.PrivateKey):
+		signer, err = NewEvmSignerFromHex(chain.PrivateKey)
+	}
+
+	// do not wrap a nil *EvmSigner into a non-nil TxSigner
+	if err != nil || signer == nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+// deriveKey implements BIP-32 private key derivation on secp256k1
+func deriveKey(seed []byte, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	key, chainCode := hmacSHA512([]byte("Bitcoin seed"), seed)
+	curveN := crypto.S256().Params().N
+
+	k := new(big.Int).SetBytes(key)
+	if k.Sign() == 0 || k.Cmp(curveN) >= 0 {
+		return nil, errors.New("invalid master key")
+	}
+
+	for _, index := range path {
+		var data []byte
+		if index >= 0x80000000 {
+			// hardened child: 0x00 || ser256(k) || ser32(i)
+			data = append([]byte{0}, paddedKeyBytes(k)...)
+		} else {
+			// normal child: serP(point(k)) || ser32(i)
+			pk, err := crypto.ToECDSA(paddedKeyBytes(k))
+			if err != nil {
+				return nil, err
+			}
+			data = crypto.CompressPubkey(&pk.PublicKey)
+		}
+		data = binary.BigEndian.AppendUint32(data, index)
+
+		il, ir := hmacSHA512(chainCode, data)
+		tweak := new(big.Int).SetBytes(il)
+		if tweak.Cmp(curveN) >= 0 {
+			return nil, fmt.Errorf("invalid child key at index %d", index)
+		}
+		k = tweak.Add(tweak, k)
+		k.Mod(k, curveN)
+		if k.Sign() == 0 {
+			return nil, fmt.Errorf("invalid child key at index %d", index)
+		}
+		chainCode = ir
+	}
+
+	return crypto.ToECDSA(paddedKeyBytes(k))
+}
+
+func hmacSHA512(key, data []byte) ([]byte, []byte) {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	return sum[:32], sum[32:]
+}
+
+func paddedKeyBytes(k *big.Int) []byte {
+	out := make([]byte, 32)
+	return k.FillBytes(out)
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"crypto-trade-client/common/config"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeystore(t *testing.T, password string) (string, *keystore.Key) {
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	key := &keystore.Key{
+		Id:         uuid.New(),
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}
+	data, err := keystore.EncryptKey(key, password, keystore.LightScryptN, keystore.LightScryptP)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "key.json")
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path, key
+}
+
+func TestLoadKeystoreSigner(t *testing.T) {
+	path, key := writeKeystore(t, "secret")
+
+	t.Setenv("TEST_KEYSTORE_PASSWORD", "secret")
+	signer, err := LoadKeystoreSigner(path, PasswordFromEnv("TEST_KEYSTORE_PASSWORD"))
+	require.NoError(t, err)
+	assert.Equal(t, key.Address, signer.PublicAddress)
+
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("secret\n"), 0600))
+	signer, err = LoadKeystoreSigner(path, PasswordFromFile(passwordFile))
+	require.NoError(t, err)
+	assert.Equal(t, key.Address, signer.PublicAddress)
+
+	_, err = LoadKeystoreSigner(path, func() (string, error) { return "wrong", nil })
+	assert.Error(t, err)
+
+	_, err = LoadKeystoreSigner(path, PasswordFromEnv("TEST_KEYSTORE_PASSWORD_NOT_SET"))
+	assert.Error(t, err)
+}
+
+func TestDeriveKey_BIP32Vector(t *testing.T) {
+	// test vector 1 of https://github.com/bitcoin/bips/blob/master/bip-0032.mediawiki
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"m", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+		{"m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{"m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{"m/0'/1/2'/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var path accounts.DerivationPath
+			if tt.path != "m" {
+				var err error
+				path, err = accounts.ParseDerivationPath(tt.path)
+				require.NoError(t, err)
+			}
+			key, err := deriveKey(seed, path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, hex.EncodeToString(crypto.FromECDSA(key)))
+		})
+	}
+}
+
+func TestLoadMnemonicSigner(t *testing.T) {
+	// well known development mnemonic of hardhat and anvil
+	mnemonic := "test test test test test test test test test test test junk"
+
+	signer, err := LoadMnemonicSigner(mnemonic, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266", signer.PublicAddress.Hex())
+
+	signer, err = LoadMnemonicSigner(mnemonic, "", "m/44'/60'/0'/0/1")
+	require.NoError(t, err)
+	assert.Equal(t, "0x70997970C51812dc3A010C7d01b50e0d17dc79C8", signer.PublicAddress.Hex())
+
+	_, err = LoadMnemonicSigner("test test test", "", "")
+	assert.Error(t, err)
+}
+
+func TestLoadSigner(t *testing.T) {
+	path, key := writeKeystore(t, "secret")
+	t.Setenv("TEST_KEYSTORE_PASSWORD", "secret")
+
+	signer, err := LoadSigner(config.Chain{Name: "optimism", Keystore: path, PasswordEnv: "TEST_KEYSTORE_PASSWORD"})
+	require.NoError(t, err)
+	assert.Equal(t, key.Address, signer.Address())
+
+	privkey := make([]byte, 32)
+	_, _ = rand.Read(privkey)
+	signer, err = LoadSigner(config.Chain{Name: "optimism", PrivateKey: hex.EncodeToString(privkey)})
+	require.NoError(t, err)
+	assert.NotNil(t, signer)
+
+	signer, err = LoadSigner(config.Chain{Name: "optimism"})
+	require.NoError(t, err)
+	assert.Nil(t, signer)
+}
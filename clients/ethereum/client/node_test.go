@@ -0,0 +1,285 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"crypto-trade-client/common/web"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNode is a local stand-in of an EVM json-rpc node.
+// Blocks are kept by height, so a reorg is simulated by replacing the hash at a height.
+type fakeNode struct {
+	lock    sync.Mutex
+	server  *httptest.Server
+	chainID uint64
+	blocks  map[uint64]string // height -> hash
+	latest  uint64
+	// finalized is the height served for the finalized tag, 0 rejects the tag like a chain without finality
+	finalized uint64
+	receipts  map[string]map[string]interface{}
+
+	// tx pool of transactions sent by eth_sendRawTransaction
+	gasPrice uint64
+	tip      uint64
+	nonce    uint64
+	pool     map[string]*types.Transaction
+
+	// batches counts the batch requests served
+	batches int
+	// traces is the X-Request-Id of the last request of each method
+	traces map[string]string
+
+	// onCall is called for every request, it can be used to advance the chain
+	onCall func(method string)
+	// handlers answers the methods not served by the built-in chain state
+	handlers map[string]func(params []json.RawMessage) (interface{}, error)
+}
+
+func newFakeNode(t *testing.T) *fakeNode {
+	n := &fakeNode{
+		chainID:  10,
+		blocks:   make(map[uint64]string),
+		receipts: make(map[string]map[string]interface{}),
+		gasPrice: 2000000000,
+		tip:      100000000,
+		pool:     make(map[string]*types.Transaction),
+		traces:   make(map[string]string),
+		handlers: make(map[string]func(params []json.RawMessage) (interface{}, error)),
+	}
+	n.server = httptest.NewServer(http.HandlerFunc(n.serve))
+	t.Cleanup(n.server.Close)
+	return n
+}
+
+func (n *fakeNode) URL() string {
+	return n.server.URL
+}
+
+func (n *fakeNode) newClient(t *testing.T, privateKeyHex string) *EthClient {
+	c, err := NewEthClient(n.URL(), "fake", privateKeyHex)
+	require.NoError(t, err)
+	return c
+}
+
+// mine appends a block on top of the chain
+func (n *fakeNode) mine() uint64 {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.latest++
+	n.blocks[n.latest] = blockHash(n.latest, "")
+	return n.latest
+}
+
+// replaceBlock replaces the block at height with a fork block
+func (n *fakeNode) replaceBlock(height uint64, fork string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.blocks[height] = blockHash(height, fork)
+}
+
+func (n *fakeNode) setReceipt(txHash string, receipt map[string]interface{}) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if receipt == nil {
+		delete(n.receipts, txHash)
+		return
+	}
+	n.receipts[txHash] = receipt
+}
+
+func (n *fakeNode) handle(method string, h func(params []json.RawMessage) (interface{}, error)) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.handlers[method] = h
+}
+
+func blockHash(height uint64, fork string) string {
+	return crypto.Keccak256Hash([]byte(fmt.Sprintf("%d-%s", height, fork))).Hex()
+}
+
+type fakeRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+func (n *fakeNode) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+
+	if len(body) > 0 && body[0] == '[' {
+		var reqs []fakeRequest
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		n.lock.Lock()
+		n.batches++
+		n.lock.Unlock()
+		resps := make([]map[string]interface{}, len(reqs))
+		for i, req := range reqs {
+			n.trace(req.Method, r)
+			resps[i] = n.respond(req)
+		}
+		_ = json.NewEncoder(w).Encode(resps)
+		return
+	}
+
+	var req fakeRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	n.trace(req.Method, r)
+	_ = json.NewEncoder(w).Encode(n.respond(req))
+}
+
+func (n *fakeNode) trace(method string, r *http.Request) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.traces[method] = r.Header.Get(web.HdrRequestID)
+}
+
+// traceOf returns the X-Request-Id of the last request of the method
+func (n *fakeNode) traceOf(method string) string {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.traces[method]
+}
+
+func (n *fakeNode) respond(req fakeRequest) map[string]interface{} {
+	if n.onCall != nil {
+		n.onCall(req.Method)
+	}
+
+	result, err := n.call(req.Method, req.Params)
+	resp := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      req.ID,
+	}
+	if err != nil {
+		resp["error"] = map[string]interface{}{"code": -32000, "message": err.Error()}
+	} else {
+		resp["result"] = result
+	}
+	return resp
+}
+
+func (n *fakeNode) call(method string, params []json.RawMessage) (interface{}, error) {
+	n.lock.Lock()
+	h, ok := n.handlers[method]
+	n.lock.Unlock()
+	if ok {
+		return h(params)
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	switch method {
+	case "eth_chainId":
+		return hexutil.EncodeUint64(n.chainID), nil
+	case "eth_blockNumber":
+		return hexutil.EncodeUint64(n.latest), nil
+	case "eth_getBlockByNumber":
+		var tag string
+		_ = json.Unmarshal(params[0], &tag)
+		height := n.latest
+		if tag == "finalized" {
+			if n.finalized == 0 {
+				return nil, fmt.Errorf("%q tag not supported on pre-merge network", tag)
+			}
+			height = n.finalized
+		} else if tag != "latest" && tag != "pending" {
+			h, err := hexutil.DecodeUint64(tag)
+			if err != nil {
+				return nil, err
+			}
+			height = h
+		}
+		hash, ok := n.blocks[height]
+		if !ok {
+			return nil, nil
+		}
+		return n.block(height, hash), nil
+	case "eth_getBlockByHash":
+		var hash string
+		_ = json.Unmarshal(params[0], &hash)
+		for height, h := range n.blocks {
+			if h == hash {
+				return n.block(height, h), nil
+			}
+		}
+		return nil, nil
+	case "eth_getTransactionReceipt":
+		var hash string
+		_ = json.Unmarshal(params[0], &hash)
+		receipt, ok := n.receipts[hash]
+		if !ok {
+			return nil, nil
+		}
+		return receipt, nil
+	case "eth_gasPrice":
+		return hexutil.EncodeUint64(n.gasPrice), nil
+	case "eth_maxPriorityFeePerGas":
+		return hexutil.EncodeUint64(n.tip), nil
+	case "eth_getTransactionCount":
+		return hexutil.EncodeUint64(n.nonce), nil
+	case "eth_sendRawTransaction":
+		var raw hexutil.Bytes
+		if err := json.Unmarshal(params[0], &raw); err != nil {
+			return nil, err
+		}
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(raw); err != nil {
+			return nil, err
+		}
+		n.pool[tx.Hash().Hex()] = tx
+		return tx.Hash().Hex(), nil
+	case "eth_getTransactionByHash":
+		var hash string
+		_ = json.Unmarshal(params[0], &hash)
+		tx, ok := n.pool[hash]
+		if !ok {
+			return nil, nil
+		}
+		bz, err := tx.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		var pending map[string]interface{}
+		if err = json.Unmarshal(bz, &pending); err != nil {
+			return nil, err
+		}
+		pending["blockNumber"] = nil
+		pending["blockHash"] = nil
+		return pending, nil
+	}
+	return nil, fmt.Errorf("method %s not supported by fake node", method)
+}
+
+func (n *fakeNode) block(height uint64, hash string) map[string]interface{} {
+	parent := ""
+	if height > 0 {
+		parent = n.blocks[height-1]
+	}
+	return map[string]interface{}{
+		"hash":          hash,
+		"parentHash":    parent,
+		"number":        hexutil.EncodeUint64(height),
+		"timestamp":     hexutil.EncodeUint64(1700000000 + height*2),
+		"baseFeePerGas": hexutil.EncodeUint64(1000000000),
+		"transactions":  []interface{}{},
+	}
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"sort"
+	"sync"
+)
+
+// NonceFetcher returns the next nonce of the address known by the node, including pending transactions
+type NonceFetcher func(ctx context.Context, address common.Address) (uint64, error)
+
+// NonceManager hands out sequential nonces per address, so that concurrent senders
+// sharing one address do not race on eth_getTransactionCount.
+//
+// The first Acquire of an address syncs the nonce from the node, after that nonces
+// are counted locally. Nonces that were never broadcast are given back with Release
+// and reused first to avoid gaps. Reset drops the local state, so the next Acquire
+// resyncs from the node, e.g. after the node rejected a transaction.
+//
+// Every Reset starts a new generation of the address: a nonce acquired before it may be
+// handed out again after the resync, so its Release is ignored.
+// The addresses are locked separately, a slow sync of one address does not hold up the others.
+type NonceManager struct {
+	lock     sync.Mutex // guards accounts only
+	fetch    NonceFetcher
+	accounts map[common.Address]*accountNonce
+}
+
+type accountNonce struct {
+	lock       sync.Mutex
+	synced     bool     // next is synced from the node
+	generation uint64   // incremented by every Reset
+	next       uint64   // next fresh nonce
+	released   []uint64 // nonces below next given back by failed sends, sorted ascending
+}
+
+func NewNonceManager(fetch NonceFetcher) *NonceManager {
+	return &NonceManager{
+		fetch:    fetch,
+		accounts: make(map[common.Address]*accountNonce),
+	}
+}
+
+func (m *NonceManager) account(address common.Address) *accountNonce {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	acc, ok := m.accounts[address]
+	if !ok {
+		acc = &accountNonce{}
+		m.accounts[address] = acc
+	}
+	return acc
+}
+
+// Acquire returns the nonce for the next transaction of address and the generation it belongs to,
+// both are given back to Release
+func (m *NonceManager) Acquire(ctx context.Context, address common.Address) (uint64, uint64, error) {
+	acc := m.account(address)
+	acc.lock.Lock()
+	defer acc.lock.Unlock()
+
+	if !acc.synced {
+		next, err := m.fetch(ctx, address)
+		if err != nil {
+			return 0, 0, err
+		}
+		acc.next = next
+		acc.synced = true
+	}
+
+	if len(acc.released) > 0 {
+		nonce := acc.released[0]
+		acc.released = acc.released[1:]
+		return nonce, acc.generation, nil
+	}
+
+	nonce := acc.next
+	acc.next++
+	return nonce, acc.generation, nil
+}
+
+// Release gives back a nonce whose transaction was never broadcast
+func (m *NonceManager) Release(address common.Address, nonce uint64, generation uint64) {
+	acc := m.account(address)
+	acc.lock.Lock()
+	defer acc.lock.Unlock()
+
+	if !acc.synced || generation != acc.generation || nonce >= acc.next {
+		// the account was resynced in between, the nonce is stale
+		return
+	}
+	for _, n := range acc.released {
+		if n == nonce {
+			return
+		}
+	}
+
+	acc.released = append(acc.released, nonce)
+	sort.Slice(acc.released, func(i, j int) bool { return acc.released[i] < acc.released[j] })
+
+	// shrink next while the highest nonces are released
+	for len(acc.released) > 0 && acc.released[len(acc.released)-1] == acc.next-1 {
+		acc.released = acc.released[:len(acc.released)-1]
+		acc.next--
+	}
+}
+
+// Reset drops the local nonce of address, the next Acquire resyncs it from the node
+func (m *NonceManager) Reset(address common.Address) {
+	acc := m.account(address)
+	acc.lock.Lock()
+	defer acc.lock.Unlock()
+
+	acc.synced = false
+	acc.released = nil
+	acc.generation++
+}
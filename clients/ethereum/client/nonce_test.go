@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNonceSource struct {
+	lock  sync.Mutex
+	nonce uint64
+	calls int
+	err   error
+}
+
+func (f *fakeNonceSource) fetch(ctx context.Context, address common.Address) (uint64, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.calls++
+	return f.nonce, f.err
+}
+
+func TestNonceManager_ConcurrentAcquire(t *testing.T) {
+	src := &fakeNonceSource{nonce: 7}
+	m := NewNonceManager(src.fetch)
+	addr := common.HexToAddress("0x9548251949b08521F4397cDfafbB58b50571a2e6")
+
+	const workers = 50
+	nonces := make([]uint64, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			n, _, err := m.Acquire(context.Background(), addr)
+			assert.NoError(t, err)
+			nonces[i] = n
+		}(i)
+	}
+	wg.Wait()
+
+	sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+	for i, n := range nonces {
+		assert.Equal(t, uint64(7+i), n)
+	}
+	assert.Equal(t, 1, src.calls, "nonce should be synced from the node only once")
+}
+
+func TestNonceManager_Release(t *testing.T) {
+	src := &fakeNonceSource{nonce: 0}
+	m := NewNonceManager(src.fetch)
+	addr := common.HexToAddress("0x9548251949b08521F4397cDfafbB58b50571a2e6")
+	ctx := context.Background()
+
+	var generation uint64
+	for i := 0; i < 4; i++ {
+		n, g, err := m.Acquire(ctx, addr)
+		require.NoError(t, err)
+		require.Equal(t, uint64(i), n)
+		generation = g
+	}
+
+	// a gap in the middle is filled first
+	m.Release(addr, 1, generation)
+	n, _, err := m.Acquire(ctx, addr)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), n)
+
+	// releasing the highest nonces rewinds the counter
+	m.Release(addr, 2, generation)
+	m.Release(addr, 3, generation)
+	n, _, err = m.Acquire(ctx, addr)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), n)
+	n, _, err = m.Acquire(ctx, addr)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), n)
+	n, _, err = m.Acquire(ctx, addr)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), n)
+
+	// unknown nonces are ignored
+	m.Release(addr, 100, generation)
+	n, _, err = m.Acquire(ctx, addr)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), n)
+}
+
+func TestNonceManager_Reset(t *testing.T) {
+	src := &fakeNonceSource{nonce: 3}
+	m := NewNonceManager(src.fetch)
+	addr := common.HexToAddress("0x9548251949b08521F4397cDfafbB58b50571a2e6")
+	ctx := context.Background()
+
+	n, _, err := m.Acquire(ctx, addr)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), n)
+
+	src.nonce = 10
+	m.Reset(addr)
+	n, _, err = m.Acquire(ctx, addr)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), n)
+	assert.Equal(t, 2, src.calls)
+
+	// a failed sync keeps the account unsynced
+	src.err = errors.New("node unavailable")
+	m.Reset(addr)
+	_, _, err = m.Acquire(ctx, addr)
+	assert.Error(t, err)
+	src.err = nil
+	n, _, err = m.Acquire(ctx, addr)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), n)
+}
+
+func TestNonceManager_ReleaseAfterReset(t *testing.T) {
+	src := &fakeNonceSource{nonce: 5}
+	m := NewNonceManager(src.fetch)
+	addr := common.HexToAddress("0x9548251949b08521F4397cDfafbB58b50571a2e6")
+	ctx := context.Background()
+
+	// A holds 5, B holds 6, C holds 7
+	_, _, err := m.Acquire(ctx, addr)
+	require.NoError(t, err)
+	b, bGeneration, err := m.Acquire(ctx, addr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), b)
+	_, _, err = m.Acquire(ctx, addr)
+	require.NoError(t, err)
+
+	// A's send fails, the node never saw 5 to 7 and the next senders get 5 and 6 again
+	m.Reset(addr)
+	for _, want := range []uint64{5, 6} {
+		n, _, err := m.Acquire(ctx, addr)
+		require.NoError(t, err)
+		require.Equal(t, want, n)
+	}
+
+	// B's sign fails, its 6 belongs to the previous generation and is held by a new sender
+	m.Release(addr, b, bGeneration)
+	n, _, err := m.Acquire(ctx, addr)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), n)
+}
+
+func TestNonceManager_SlowSync(t *testing.T) {
+	slow := common.HexToAddress("0x9548251949b08521F4397cDfafbB58b50571a2e6")
+	fast := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	unblock := make(chan struct{})
+	m := NewNonceManager(func(ctx context.Context, address common.Address) (uint64, error) {
+		if address == slow {
+			<-unblock
+		}
+		return 1, nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _, err := m.Acquire(context.Background(), slow)
+		assert.NoError(t, err)
+	}()
+
+	// the sync of the slow address does not hold up the other addresses
+	n, _, err := m.Acquire(context.Background(), fast)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), n)
+	close(unblock)
+	<-done
+}
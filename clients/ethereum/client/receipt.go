@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"crypto-trade-client/clients/ethereum"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/hashicorp/go-hclog"
+	"math/big"
+	"time"
+)
+
+const defaultReceiptPollInterval = 2 * time.Second
+
+var (
+	// ErrTxReverted is returned together with the receipt when the transaction was mined with a failed status
+	ErrTxReverted = errors.New("transaction reverted")
+)
+
+// ReceiptResult is the outcome of a transaction confirmed by WaitForReceipt
+type ReceiptResult struct {
+	Receipt ethereum.Receipt
+
+	TxHash      common.Hash
+	BlockHash   common.Hash
+	BlockNumber int64
+	// Confirmations is the number of blocks on top of the receipt's block
+	Confirmations int64
+	Success       bool
+
+	// GasFee is gas used * effective gas price paid on the execution layer
+	GasFee *big.Int
+	// L1Fee is the data fee paid to L1 on rollups like Optimism, zero on other chains
+	L1Fee *big.Int
+	// EffectiveFee is the total fee paid by the sender, GasFee + L1Fee
+	EffectiveFee *big.Int
+}
+
+// WithReceiptPollInterval sets how often WaitForReceipt polls the node, default is 2 seconds
+func (ec *EthClient) WithReceiptPollInterval(interval time.Duration) *EthClient {
+	ec.receiptPollInterval = interval
+	return ec
+}
+
+// GetTransactionReceipt returns the receipt of the transaction, ethereum.ErrTxNotFound if it is not mined yet
+func (ec *EthClient) GetTransactionReceipt(hash common.Hash) (*ethereum.Receipt, error) {
+	receipt, err := ec.ethRpc.GetTransactionReceipt(hash.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if receipt.TransactionHash == "" || receipt.BlockHash == "" {
+		return nil, ethereum.ErrTxNotFound
+	}
+	return &receipt, nil
+}
+
+// WaitForReceipt polls the receipt of the transaction until it has `confirmations` blocks on top of it,
+// confirmations 0 returns as soon as the transaction is mined.
+// When the block of the receipt is replaced by a reorg it keeps waiting for the transaction to be mined again.
+// A mined transaction with failed status returns the result together with ErrTxReverted.
+func (ec *EthClient) WaitForReceipt(ctx context.Context, hash common.Hash, confirmations int64) (*ReceiptResult, error) {
+	interval := ec.receiptPollInterval
+	if interval <= 0 {
+		interval = defaultReceiptPollInterval
+	}
+	logger := hclog.L().Named("eth-receipt").With("tx", hash.Hex())
+
+	for {
+		result, err := ec.checkReceipt(ctx, hash)
+		switch {
+		case err == nil:
+			if result.Confirmations >= confirmations {
+				if !result.Success {
+					return result, ErrTxReverted
+				}
+				return result, nil
+			}
+			logger.Trace("waiting for confirmations", "block", result.BlockNumber, "confirmations", result.Confirmations)
+		case errors.Is(err, errReceiptReorged):
+			logger.Warn("receipt block is no longer canonical, waiting for the transaction to be mined again")
+		case errors.Is(err, ethereum.ErrTxNotFound):
+			logger.Trace("transaction is not mined yet")
+		default:
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+var errReceiptReorged = errors.New("receipt block is reorged")
+
+// checkReceipt fetches the receipt and makes sure its block is still the canonical one at its height
+func (ec *EthClient) checkReceipt(ctx context.Context, hash common.Hash) (*ReceiptResult, error) {
+	receipt, err := ec.GetTransactionReceipt(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	height, err := hexutil.DecodeUint64(receipt.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := ec.ethRpc.GetBlockByNumber(ctx, ethereum.EthBlockNumArg(height), false)
+	if err != nil {
+		return nil, err
+	}
+	if common.HexToHash(block.Hash) != common.HexToHash(receipt.BlockHash) {
+		return nil, errReceiptReorged
+	}
+
+	latest, err := ec.latestBlockHeight(ctx)
+	if err != nil {
+		return nil, err
+	}
+	confirmations := latest - int64(height)
+	if confirmations < 0 {
+		confirmations = 0
+	}
+
+	return newReceiptResult(receipt, int64(height), confirmations), nil
+}
+
+func newReceiptResult(receipt *ethereum.Receipt, height int64, confirmations int64) *ReceiptResult {
+	gasFee := new(big.Int)
+	if receipt.GasUsed != nil && receipt.EffectiveGasPrice != nil {
+		gasFee.Mul(receipt.GasUsed.ToInt(), receipt.EffectiveGasPrice.ToInt())
+	}
+	l1Fee := new(big.Int)
+	if receipt.L1Fee != nil {
+		l1Fee.Set(receipt.L1Fee.ToInt())
+	}
+
+	return &ReceiptResult{
+		Receipt:       *receipt,
+		TxHash:        common.HexToHash(receipt.TransactionHash),
+		BlockHash:     common.HexToHash(receipt.BlockHash),
+		BlockNumber:   height,
+		Confirmations: confirmations,
+		Success:       uint64(receipt.Status) == types.ReceiptStatusSuccessful,
+		GasFee:        gasFee,
+		L1Fee:         l1Fee,
+		EffectiveFee:  new(big.Int).Add(gasFee, l1Fee),
+	}
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTxHash = "0x86b422e9611adacde5c7f06ce48d60ac9d3dd1c448541fdc31ad887aaba8ede3"
+
+func testReceipt(node *fakeNode, height uint64, status uint64) map[string]interface{} {
+	return map[string]interface{}{
+		"transactionHash":   testTxHash,
+		"blockNumber":       hexutil.EncodeUint64(height),
+		"blockHash":         node.blocks[height],
+		"gasUsed":           hexutil.EncodeUint64(21000),
+		"effectiveGasPrice": hexutil.EncodeUint64(2000000000),
+		"status":            hexutil.EncodeUint64(status),
+		"logs":              []interface{}{},
+		"l1Fee":             hexutil.EncodeUint64(5000),
+	}
+}
+
+func TestEthClient_WaitForReceipt(t *testing.T) {
+	node := newFakeNode(t)
+	for i := 0; i < 10; i++ {
+		node.mine()
+	}
+	// the transaction is mined at the next block, then the chain keeps growing while polling
+	minedAt := node.mine()
+	node.setReceipt(testTxHash, testReceipt(node, minedAt, 1))
+	node.onCall = func(method string) {
+		if method == "eth_getTransactionReceipt" {
+			node.mine()
+		}
+	}
+
+	client := node.newClient(t, "").WithReceiptPollInterval(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := client.WaitForReceipt(ctx, common.HexToHash(testTxHash), 3)
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, int64(minedAt), result.BlockNumber)
+	assert.GreaterOrEqual(t, result.Confirmations, int64(3))
+	assert.Equal(t, int64(21000*2000000000), result.GasFee.Int64())
+	assert.Equal(t, int64(5000), result.L1Fee.Int64())
+	assert.Equal(t, int64(21000*2000000000+5000), result.EffectiveFee.Int64())
+}
+
+func TestEthClient_WaitForReceiptReverted(t *testing.T) {
+	node := newFakeNode(t)
+	minedAt := node.mine()
+	node.setReceipt(testTxHash, testReceipt(node, minedAt, 0))
+
+	client := node.newClient(t, "").WithReceiptPollInterval(10 * time.Millisecond)
+	result, err := client.WaitForReceipt(context.Background(), common.HexToHash(testTxHash), 0)
+	assert.ErrorIs(t, err, ErrTxReverted)
+	require.NotNil(t, result)
+	assert.False(t, result.Success)
+}
+
+func TestEthClient_WaitForReceiptReorg(t *testing.T) {
+	node := newFakeNode(t)
+	minedAt := node.mine()
+	node.setReceipt(testTxHash, testReceipt(node, minedAt, 1))
+	// the receipt's block is orphaned, the node still serves the stale receipt for a while
+	node.replaceBlock(minedAt, "fork")
+
+	polls := 0
+	node.onCall = func(method string) {
+		if method != "eth_getTransactionReceipt" {
+			return
+		}
+		polls++
+		if polls == 3 {
+			// the transaction is included again in the canonical chain
+			height := node.mine()
+			node.setReceipt(testTxHash, testReceipt(node, height, 1))
+		}
+	}
+
+	client := node.newClient(t, "").WithReceiptPollInterval(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := client.WaitForReceipt(ctx, common.HexToHash(testTxHash), 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(minedAt+1), result.BlockNumber)
+	assert.GreaterOrEqual(t, polls, 3)
+}
+
+func TestEthClient_WaitForReceiptCanceled(t *testing.T) {
+	node := newFakeNode(t)
+	node.mine()
+
+	client := node.newClient(t, "").WithReceiptPollInterval(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := client.WaitForReceipt(ctx, common.HexToHash(testTxHash), 0)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"crypto-trade-client/common/web"
+	"crypto-trade-client/common/web/fetch"
+	"crypto-trade-client/common/web/sign"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/hashicorp/go-hclog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultRemoteSignPath is the path of the signing service's EVM transaction endpoint
+const DefaultRemoteSignPath = "/evm/sign"
+
+// RemoteSignRequest is the body posted to the signing service
+type RemoteSignRequest struct {
+	Address string        `json:"address"`
+	ChainID string        `json:"chainId"` // decimal chain id
+	Tx      hexutil.Bytes `json:"tx"`      // binary encoding of the unsigned transaction
+}
+
+// RemoteSignResponse is the body returned by the signing service
+type RemoteSignResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"msg,omitempty"`
+	Data    struct {
+		SignedTx hexutil.Bytes `json:"signedTx"` // binary encoding of the signed transaction
+	} `json:"data"`
+}
+
+// RemoteSigner is a TxSigner that asks a signing service over HTTP to sign transactions.
+// Requests are authenticated with the same scheme as middleware.CheckSign: the X-Chain-Appid
+// and X-Chain-Sign headers, the signature covering sign.Construct of the request.
+type RemoteSigner struct {
+	address common.Address
+	url     string
+	uri     string
+	appId   string
+	keys    *sign.Keys
+	client  *fetch.Client
+}
+
+// NewRemoteSigner creates a signer of address backed by the signing service at endpoint.
+// keys signs the requests of appId, only its private key is used.
+func NewRemoteSigner(endpoint string, appId string, keys *sign.Keys, address common.Address, logger hclog.Logger) (*RemoteSigner, error) {
+	return NewRemoteSignerWithPath(endpoint, DefaultRemoteSignPath, appId, keys, address, logger)
+}
+
+// NewRemoteSignerWithPath is NewRemoteSigner with a custom path of the sign endpoint
+func NewRemoteSignerWithPath(endpoint string, path string, appId string, keys *sign.Keys, address common.Address, logger hclog.Logger) (*RemoteSigner, error) {
+	u, err := url.Parse(strings.TrimRight(endpoint, "/") + path)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsAbs() {
+		return nil, fmt.Errorf("remote signer endpoint %s is not absolute", endpoint)
+	}
+
+	return &RemoteSigner{
+		address: address,
+		url:     u.String(),
+		uri:     u.RequestURI(),
+		appId:   appId,
+		keys:    keys,
+		client:  fetch.NewClient(logger),
+	}, nil
+}
+
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+func (s *RemoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	unsigned, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(RemoteSignRequest{
+		Address: s.address.Hex(),
+		ChainID: chainID.String(),
+		Tx:      unsigned,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := s.keys.Sign(sign.Construct(s.uri, http.MethodPost, web.ApplicationJSON, "", s.appId, body))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Post(s.url).
+		WithContext(ctx).
+		SetHeaders(map[string]string{
+			web.HdrContentType: web.ApplicationJSON,
+			"X-Chain-Appid":    s.appId,
+			"X-Chain-Sign":     signature,
+		}).
+		SetBody(body).
+		Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	var signResp RemoteSignResponse
+	if err = json.Unmarshal(resp.BodyBytes(), &signResp); err != nil {
+		return nil, err
+	}
+	if len(signResp.Data.SignedTx) == 0 {
+		return nil, fmt.Errorf("remote signer returned no transaction, code %d: %s", signResp.Code, signResp.Message)
+	}
+
+	signed := new(types.Transaction)
+	if err = signed.UnmarshalBinary(signResp.Data.SignedTx); err != nil {
+		return nil, err
+	}
+
+	// never broadcast something else than what we asked to sign
+	txSigner := txSignerFor(tx, chainID)
+	if txSigner.Hash(signed) != txSigner.Hash(tx) {
+		return nil, errors.New("remote signer signed a different transaction")
+	}
+	from, err := types.Sender(txSigner, signed)
+	if err != nil {
+		return nil, err
+	}
+	if from != s.address {
+		return nil, fmt.Errorf("remote signer signed with %s, expected %s", from.Hex(), s.address.Hex())
+	}
+
+	return signed, nil
+}
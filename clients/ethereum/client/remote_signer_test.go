@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"crypto-trade-client/common/web/middleware"
+	"crypto-trade-client/common/web/sign"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gin-gonic/gin"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSigningService starts a signing service guarded by middleware.CheckSign,
+// it signs with key, or tampers the transaction when tamper is set
+func newSigningService(t *testing.T, appId string, requestKey []byte, key *EvmSigner, tamper bool) *httptest.Server {
+	serviceKeys, err := sign.NewService("00", map[string]string{"-" + appId: hex.EncodeToString(requestKey)})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middleware.CheckSign(serviceKeys))
+	engine.POST("/api"+DefaultRemoteSignPath, func(c *gin.Context) {
+		var req RemoteSignRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": err.Error()})
+			return
+		}
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(req.Tx); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": err.Error()})
+			return
+		}
+		if tamper {
+			tx = types.NewTx(&types.LegacyTx{Nonce: tx.Nonce(), GasPrice: tx.GasPrice(), Gas: tx.Gas(), To: &common.Address{}, Value: big.NewInt(1e18)})
+		}
+		chainID, _ := new(big.Int).SetString(req.ChainID, 10)
+		signed, err := key.SignTx(c, tx, chainID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "msg": err.Error()})
+			return
+		}
+		raw, _ := signed.MarshalBinary()
+		c.JSON(http.StatusOK, gin.H{"code": 0, "data": gin.H{"signedTx": hexutil.Bytes(raw)}})
+	})
+
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newRequestKeys(t *testing.T) (*sign.Keys, []byte) {
+	requestKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	keys, err := sign.NewService(hex.EncodeToString(crypto.FromECDSA(requestKey)), nil)
+	require.NoError(t, err)
+	return keys, crypto.FromECDSAPub(&requestKey.PublicKey)
+}
+
+func TestRemoteSigner_SignTx(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	key := NewEvmSigner(privateKey)
+	keys, requestPub := newRequestKeys(t)
+	server := newSigningService(t, "payout", requestPub, key, false)
+
+	signer, err := NewRemoteSigner(server.URL+"/api", "payout", keys, key.Address(), hclog.NewNullLogger())
+	require.NoError(t, err)
+
+	to := common.HexToAddress("0xebdBa70B23edf9A69B7872b5Ff9A6Ba55e2F2FE4")
+	chainID := big.NewInt(10)
+	for _, txData := range []types.TxData{
+		&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1e9), Gas: 21000, To: &to, Value: big.NewInt(1)},
+		&types.DynamicFeeTx{ChainID: chainID, Nonce: 2, GasTipCap: big.NewInt(1e8), GasFeeCap: big.NewInt(2e9), Gas: 21000, To: &to, Value: big.NewInt(1)},
+	} {
+		tx := types.NewTx(txData)
+		signed, err := signer.SignTx(context.Background(), tx, chainID)
+		require.NoError(t, err)
+
+		from, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+		require.NoError(t, err)
+		assert.Equal(t, key.Address(), from)
+		assert.Equal(t, tx.Nonce(), signed.Nonce())
+	}
+}
+
+func TestRemoteSigner_Rejected(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	key := NewEvmSigner(privateKey)
+	_, requestPub := newRequestKeys(t)
+	otherKeys, _ := newRequestKeys(t)
+	server := newSigningService(t, "payout", requestPub, key, false)
+
+	// requests signed by an unknown key are refused by CheckSign
+	signer, err := NewRemoteSigner(server.URL+"/api", "payout", otherKeys, key.Address(), hclog.NewNullLogger())
+	require.NoError(t, err)
+
+	to := common.HexToAddress("0xebdBa70B23edf9A69B7872b5Ff9A6Ba55e2F2FE4")
+	_, err = signer.SignTx(context.Background(), types.NewTx(&types.LegacyTx{Gas: 21000, GasPrice: big.NewInt(1), To: &to}), big.NewInt(10))
+	assert.Error(t, err)
+}
+
+func TestRemoteSigner_TamperedTx(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	key := NewEvmSigner(privateKey)
+	keys, requestPub := newRequestKeys(t)
+	server := newSigningService(t, "payout", requestPub, key, true)
+
+	signer, err := NewRemoteSigner(server.URL+"/api", "payout", keys, key.Address(), hclog.NewNullLogger())
+	require.NoError(t, err)
+
+	to := common.HexToAddress("0xebdBa70B23edf9A69B7872b5Ff9A6Ba55e2F2FE4")
+	_, err = signer.SignTx(context.Background(), types.NewTx(&types.LegacyTx{Gas: 21000, GasPrice: big.NewInt(1), To: &to}), big.NewInt(10))
+	assert.ErrorContains(t, err, "different transaction")
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+)
+
+// MinReplacementBumpPercent is the minimal fee bump accepted by geth's tx pool to replace a pending transaction
+const MinReplacementBumpPercent = 10
+
+var (
+	// ErrTxNotPending is returned when a transaction to replace is already mined
+	ErrTxNotPending = errors.New("transaction is not pending")
+)
+
+// SpeedUp re-sends the pending transaction with the same nonce, recipient, value and data,
+// with its fees raised by bumpPercent. bumpPercent lower than MinReplacementBumpPercent is raised to it.
+// The fees never go below what the node currently suggests. The client's max fee cap is not applied.
+func (ec *EthClient) SpeedUp(hash common.Hash, bumpPercent int) (common.Hash, error) {
+	return ec.SpeedUpContext(context.Background(), hash, bumpPercent)
+}
+
+// SpeedUpContext is SpeedUp with a context, the calls to the node carry its trace id
+func (ec *EthClient) SpeedUpContext(ctx context.Context, hash common.Hash, bumpPercent int) (common.Hash, error) {
+	return ec.replace(ctx, hash, bumpPercent, func(from common.Address, orig *types.Transaction) (replacement, error) {
+		return replacement{to: orig.To(), value: orig.Value(), gas: orig.Gas(), data: orig.Data()}, nil
+	})
+}
+
+// Cancel replaces the pending transaction with a zero value transfer to the sender itself, using the same nonce
+// and fees raised by MinReplacementBumpPercent. The gas limit is the one of Transfer.
+func (ec *EthClient) Cancel(hash common.Hash) (common.Hash, error) {
+	return ec.CancelContext(context.Background(), hash)
+}
+
+// CancelContext is Cancel with a context, the calls to the node carry its trace id
+func (ec *EthClient) CancelContext(ctx context.Context, hash common.Hash) (common.Hash, error) {
+	return ec.replace(ctx, hash, MinReplacementBumpPercent, func(from common.Address, orig *types.Transaction) (replacement, error) {
+		value := big.NewInt(0)
+		gas, err := ec.transferGas(ctx, from, from, value)
+		if err != nil {
+			return replacement{}, err
+		}
+		return replacement{to: &from, value: value, gas: gas}, nil
+	})
+}
+
+type replacement struct {
+	to    *common.Address
+	value *big.Int
+	gas   uint64
+	data  []byte
+}
+
+func (ec *EthClient) replace(ctx context.Context, hash common.Hash, bumpPercent int, build func(common.Address, *types.Transaction) (replacement, error)) (common.Hash, error) {
+	if bumpPercent < MinReplacementBumpPercent {
+		bumpPercent = MinReplacementBumpPercent
+	}
+
+	orig, isPending, err := ec.ethClient.TransactionByHash(ctx, hash)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if !isPending {
+		return common.Hash{}, ErrTxNotPending
+	}
+
+	chainID, err := ec.GetChainID(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(chainID), orig)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	txSigner, err := ec.signerOf(from)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	r, err := build(from, orig)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var txData types.TxData
+	switch orig.Type() {
+	case types.LegacyTxType:
+		gasPrice, err := ec.SuggestGasPrice(ctx)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		txData = &types.LegacyTx{
+			Nonce:    orig.Nonce(),
+			GasPrice: maxBig(bumpFee(orig.GasPrice(), bumpPercent), gasPrice),
+			Gas:      r.gas,
+			To:       r.to,
+			Value:    r.value,
+			Data:     r.data,
+		}
+	case types.DynamicFeeTxType:
+		baseFee, err := ec.latestBaseFee(ctx)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		tip, err := ec.SuggestGasTipCap(ctx)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		tipCap := maxBig(bumpFee(orig.GasTipCap(), bumpPercent), tip)
+		feeCap := bumpFee(orig.GasFeeCap(), bumpPercent)
+		if baseFee != nil {
+			feeCap = maxBig(feeCap, new(big.Int).Add(tipCap, new(big.Int).Mul(baseFee, big.NewInt(2))))
+		}
+		if tipCap.Cmp(feeCap) > 0 {
+			feeCap = tipCap
+		}
+		txData = &types.DynamicFeeTx{
+			Nonce:     orig.Nonce(),
+			GasTipCap: tipCap,
+			GasFeeCap: feeCap,
+			Gas:       r.gas,
+			To:        r.to,
+			Value:     r.value,
+			Data:      r.data,
+		}
+	default:
+		return common.Hash{}, fmt.Errorf("replacing transaction type %d is not supported", orig.Type())
+	}
+
+	signedTx, err := txSigner.SignTx(ctx, types.NewTx(txData), chainID)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	err = ec.ethClient.SendTransaction(ctx, signedTx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return signedTx.Hash(), nil
+}
+
+// bumpFee returns fee * (100 + percent) / 100, rounded up so that the bump is never below percent
+func bumpFee(fee *big.Int, percent int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(int64(100+percent)))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSendingClient(t *testing.T, node *fakeNode) (*EthClient, common.Address) {
+	_, privkey := generateKeyPair()
+	client := node.newClient(t, hex.EncodeToString(privkey))
+	return client, client.signer.Address()
+}
+
+func TestBumpFee(t *testing.T) {
+	assert.Equal(t, int64(110), bumpFee(big.NewInt(100), 10).Int64())
+	// rounded up, 10% of 15 is 1.5
+	assert.Equal(t, int64(17), bumpFee(big.NewInt(15), 10).Int64())
+	assert.Equal(t, int64(0), bumpFee(big.NewInt(0), 10).Int64())
+}
+
+func TestEthClient_SpeedUpLegacy(t *testing.T) {
+	node := newFakeNode(t)
+	node.mine()
+	node.nonce = 5
+	client, from := newSendingClient(t, node)
+	to := common.HexToAddress("0xebdBa70B23edf9A69B7872b5Ff9A6Ba55e2F2FE4")
+
+	hash, err := client.Transfer(from, to, big.NewInt(1000))
+	require.NoError(t, err)
+	orig := node.pool[hash.Hex()]
+	require.NotNil(t, orig)
+
+	newHash, err := client.SpeedUp(hash, 20)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, newHash)
+
+	replaced := node.pool[newHash.Hex()]
+	require.NotNil(t, replaced)
+	assert.Equal(t, uint8(types.LegacyTxType), replaced.Type())
+	assert.Equal(t, orig.Nonce(), replaced.Nonce())
+	assert.Equal(t, to, *replaced.To())
+	assert.Equal(t, int64(1000), replaced.Value().Int64())
+	assert.Equal(t, 0, bumpFee(orig.GasPrice(), 20).Cmp(replaced.GasPrice()))
+}
+
+func TestEthClient_CancelDynamicFee(t *testing.T) {
+	node := newFakeNode(t)
+	node.mine()
+	client, from := newSendingClient(t, node)
+	client.WithDynamicFee(nil)
+	to := common.HexToAddress("0xebdBa70B23edf9A69B7872b5Ff9A6Ba55e2F2FE4")
+
+	hash, err := client.Transfer(from, to, big.NewInt(1000))
+	require.NoError(t, err)
+	orig := node.pool[hash.Hex()]
+	require.Equal(t, uint8(types.DynamicFeeTxType), orig.Type())
+
+	newHash, err := client.Cancel(hash)
+	require.NoError(t, err)
+
+	cancel := node.pool[newHash.Hex()]
+	require.NotNil(t, cancel)
+	assert.Equal(t, uint8(types.DynamicFeeTxType), cancel.Type())
+	assert.Equal(t, orig.Nonce(), cancel.Nonce())
+	assert.Equal(t, from, *cancel.To())
+	assert.Equal(t, int64(0), cancel.Value().Int64())
+	assert.Equal(t, uint64(21000), cancel.Gas())
+	// both caps must be raised by at least 10% to be accepted by the tx pool
+	assert.True(t, cancel.GasTipCap().Cmp(bumpFee(orig.GasTipCap(), MinReplacementBumpPercent)) >= 0)
+	assert.True(t, cancel.GasFeeCap().Cmp(bumpFee(orig.GasFeeCap(), MinReplacementBumpPercent)) >= 0)
+}
+
+func TestEthClient_CancelWithGasEstimator(t *testing.T) {
+	node := newFakeNode(t)
+	node.mine()
+	client, from := newSendingClient(t, node)
+	// e.g. arbitrum, whose transfers pay for the L1 gas
+	var estimated []*big.Int
+	client.WithGasEstimator(func(ctx context.Context, from common.Address, to common.Address, value *big.Int, data []byte) (uint64, error) {
+		estimated = append(estimated, value)
+		return 95000, nil
+	})
+	to := common.HexToAddress("0xebdBa70B23edf9A69B7872b5Ff9A6Ba55e2F2FE4")
+
+	hash, err := client.Transfer(from, to, big.NewInt(1000))
+	require.NoError(t, err)
+	newHash, err := client.Cancel(hash)
+	require.NoError(t, err)
+
+	cancel := node.pool[newHash.Hex()]
+	require.NotNil(t, cancel)
+	assert.Equal(t, uint64(95000), cancel.Gas())
+	require.Len(t, estimated, 2)
+	assert.Equal(t, int64(0), estimated[1].Int64())
+
+	client.WithGasEstimator(func(ctx context.Context, from common.Address, to common.Address, value *big.Int, data []byte) (uint64, error) {
+		return 0, errors.New("execution reverted")
+	})
+	_, err = client.Cancel(hash)
+	assert.ErrorContains(t, err, "execution reverted")
+}
+
+func TestEthClient_SpeedUpUnknownSigner(t *testing.T) {
+	node := newFakeNode(t)
+	node.mine()
+	client, from := newSendingClient(t, node)
+	other, _ := newSendingClient(t, node)
+
+	hash, err := client.Transfer(from, from, big.NewInt(1))
+	require.NoError(t, err)
+
+	_, err = other.SpeedUp(hash, 10)
+	assert.Error(t, err)
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"crypto-trade-client/common/web/fetch"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func useRecorder(t *testing.T, fixture string) *fetch.Recorder {
+	recorder, err := fetch.NewRecorder(fixture, fetch.RecordMissing, nil)
+	require.NoError(t, err)
+	return recorder
+}
+
+func Test_EthClient_replay(t *testing.T) {
+	fixture := filepath.Join(t.TempDir(), "eth.json")
+	node := newFakeNode(t)
+	for i := 0; i < 3; i++ {
+		node.mine()
+	}
+
+	// both the rpc and the go-ethereum clients send their requests through the recorder
+	query := func(recorder *fetch.Recorder) (int64, int64) {
+		client, err := NewEthClientWithHTTPClient(node.URL(), "fake", "", recorder.HTTPClient())
+		require.NoError(t, err)
+		height, err := client.GetLatestBlockHeight()
+		require.NoError(t, err)
+		chainID, err := client.GetChainID(context.Background())
+		require.NoError(t, err)
+		return height, chainID.Int64()
+	}
+
+	recorder := useRecorder(t, fixture)
+	height, chainID := query(recorder)
+	require.NoError(t, recorder.Save())
+	node.server.Close()
+
+	recorder = useRecorder(t, fixture)
+	require.True(t, recorder.Replaying())
+	replayedHeight, replayedChainID := query(recorder)
+	assert.Equal(t, height, replayedHeight)
+	assert.Equal(t, chainID, replayedChainID)
+}
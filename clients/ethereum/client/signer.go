@@ -2,7 +2,13 @@ package client
 
 import (
 	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 )
 
 type EthSigner struct {
@@ -17,13 +23,109 @@ func NewEthSigner(hexKey string) (*EthSigner, error) {
 	return &EthSigner{privateKey: privateKey}, nil
 }
 
+// Address returns the address of the signer
+func (s *EthSigner) Address() common.Address {
+	return crypto.PubkeyToAddress(s.privateKey.PublicKey)
+}
+
+// SignTransaction signs a 32 bytes hash as it is, the signature's V is 0 or 1.
+//
+// Deprecated: it signs whatever hash it is given, use SignPersonalMessage or SignTypedData for messages,
+// and TxSigner for transactions.
 func (s *EthSigner) SignTransaction(tx interface{}) ([]byte, error) {
-	// Implement Ethereum transaction signing logic here
-	// For demonstration purposes; we assume tx is a byte slice
-	data := tx.([]byte)
+	data, ok := tx.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unsupported data type %T to sign", tx)
+	}
 	signature, err := crypto.Sign(data, s.privateKey)
 	if err != nil {
 		return nil, err
 	}
 	return signature, nil
 }
+
+// SignPersonalMessage signs the message with the EIP-191 prefix "\x19Ethereum Signed Message:\n" + len(message),
+// the same as personal_sign / eth_sign of wallets. The signature's V is 27 or 28.
+func (s *EthSigner) SignPersonalMessage(message []byte) ([]byte, error) {
+	return s.signHash(accounts.TextHash(message))
+}
+
+// SignTypedData signs the EIP-712 typed data given as the JSON document of eth_signTypedData_v4,
+// e.g. an EIP-2612 permit. The signature's V is 27 or 28.
+func (s *EthSigner) SignTypedData(typedDataJSON []byte) ([]byte, error) {
+	hash, err := TypedDataHash(typedDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	return s.signHash(hash)
+}
+
+func (s *EthSigner) signHash(hash []byte) ([]byte, error) {
+	signature, err := crypto.Sign(hash, s.privateKey)
+	if err != nil {
+		return nil, err
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature, nil
+}
+
+// TypedDataHash returns the EIP-712 digest keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+// of the JSON typed data document
+func TypedDataHash(typedDataJSON []byte) ([]byte, error) {
+	var typedData apitypes.TypedData
+	if err := json.Unmarshal(typedDataJSON, &typedData); err != nil {
+		return nil, fmt.Errorf("invalid typed data: %v", err)
+	}
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+	return hash, nil
+}
+
+// RecoverPersonalMessage returns the address which signed the message with SignPersonalMessage
+func RecoverPersonalMessage(message []byte, signature []byte) (common.Address, error) {
+	return recoverHash(accounts.TextHash(message), signature)
+}
+
+// VerifyPersonalMessage reports whether the message was signed by address with SignPersonalMessage
+func VerifyPersonalMessage(address common.Address, message []byte, signature []byte) bool {
+	signer, err := RecoverPersonalMessage(message, signature)
+	return err == nil && signer == address
+}
+
+// RecoverTypedData returns the address which signed the typed data with SignTypedData
+func RecoverTypedData(typedDataJSON []byte, signature []byte) (common.Address, error) {
+	hash, err := TypedDataHash(typedDataJSON)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return recoverHash(hash, signature)
+}
+
+// VerifyTypedData reports whether the typed data was signed by address with SignTypedData
+func VerifyTypedData(address common.Address, typedDataJSON []byte, signature []byte) bool {
+	signer, err := RecoverTypedData(typedDataJSON, signature)
+	return err == nil && signer == address
+}
+
+// recoverHash accepts signatures with V of 0/1 or 27/28
+func recoverHash(hash []byte, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("signature must be %d bytes long", crypto.SignatureLength)
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	if sig[crypto.RecoveryIDOffset] > 1 {
+		return common.Address{}, errors.New("invalid signature recovery id")
+	}
+
+	publicKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
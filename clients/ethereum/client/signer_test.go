@@ -4,6 +4,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/rand"
 	"encoding/hex"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/crypto"
@@ -57,3 +58,82 @@ func TestEthSigner_SignTransaction(t *testing.T) {
 
 	assert.Equal(t, expectedAddress.Hex(), recoveredAddress.Hex(), "The recovered address should match the expected address")
 }
+
+// mailTypedData is the example of https://eips.ethereum.org/EIPS/eip-712
+const mailTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func TestEthSigner_SignTypedData(t *testing.T) {
+	// private key of the EIP-712 example is keccak256("cow")
+	signer, err := NewEthSigner(hex.EncodeToString(crypto.Keccak256([]byte("cow"))))
+	assert.NoError(t, err)
+	assert.Equal(t, "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826", signer.Address().Hex())
+
+	hash, err := TypedDataHash([]byte(mailTypedData))
+	assert.NoError(t, err)
+	assert.Equal(t, "be609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2", hex.EncodeToString(hash))
+
+	signature, err := signer.SignTypedData([]byte(mailTypedData))
+	assert.NoError(t, err)
+	assert.Equal(t, "4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d"+
+		"07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b91562"+"1c", hex.EncodeToString(signature))
+
+	assert.True(t, VerifyTypedData(signer.Address(), []byte(mailTypedData), signature))
+
+	tampered := strings.Replace(mailTypedData, "Hello, Bob!", "Hello, Eve!", 1)
+	assert.False(t, VerifyTypedData(signer.Address(), []byte(tampered), signature))
+}
+
+func TestEthSigner_SignPersonalMessage(t *testing.T) {
+	_, privkey := generateKeyPair()
+	signer, err := NewEthSigner(hex.EncodeToString(privkey))
+	assert.NoError(t, err)
+
+	message := []byte("login challenge 8f1c2a")
+	signature, err := signer.SignPersonalMessage(message)
+	assert.NoError(t, err)
+	assert.Contains(t, []byte{27, 28}, signature[64])
+
+	recovered, err := RecoverPersonalMessage(message, signature)
+	assert.NoError(t, err)
+	assert.Equal(t, signer.Address(), recovered)
+	assert.True(t, VerifyPersonalMessage(signer.Address(), message, signature))
+
+	// the prefix makes the signature differ from signing the raw keccak hash
+	raw, err := signer.SignTransaction(crypto.Keccak256(message))
+	assert.NoError(t, err)
+	assert.False(t, VerifyPersonalMessage(signer.Address(), message, raw))
+
+	assert.False(t, VerifyPersonalMessage(signer.Address(), []byte("another challenge"), signature))
+	_, err = RecoverPersonalMessage(message, signature[:64])
+	assert.Error(t, err)
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"crypto-trade-client/clients/ethereum"
+	"crypto-trade-client/common/rpc"
+	"errors"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrNoSubscriptions is returned by the Subscribe methods before DialSubscriptions
+var ErrNoSubscriptions = errors.New("subscriptions need a websocket endpoint, call DialSubscriptions first")
+
+// DialSubscriptions connects to the websocket endpoint of the node, e.g. ws://localhost:8546, used by the
+// Subscribe methods. The connection is re-established when lost, until CloseSubscriptions.
+func (ec *EthClient) DialSubscriptions(ctx context.Context, wsEndpoint string) error {
+	var eSub ethereum.EthSubscription
+	closer, err := rpc.NewWebSocketClient(ctx, wsEndpoint, "eth-ws", &eSub, map[string]string{})
+	if err != nil {
+		return err
+	}
+
+	ec.CloseSubscriptions()
+	ec.ethSub = &eSub
+	ec.closeSub = closer
+	return nil
+}
+
+// CloseSubscriptions closes the websocket connection, the channels of the subscriptions are closed
+func (ec *EthClient) CloseSubscriptions() {
+	if ec.closeSub != nil {
+		ec.closeSub()
+		ec.ethSub = nil
+		ec.closeSub = nil
+	}
+}
+
+// SubscribeNewHeads streams the headers of the new blocks until ctx is done, the blocks have no transactions.
+// After a reorg the headers of the new canonical chain are streamed again from the fork point,
+// and the headers produced while the connection is re-established are skipped.
+func (ec *EthClient) SubscribeNewHeads(ctx context.Context) (<-chan ethereum.Block, error) {
+	if ec.ethSub == nil {
+		return nil, ErrNoSubscriptions
+	}
+	return ec.ethSub.NewHeads(ctx)
+}
+
+// SubscribeLogs streams the logs matching the filter until ctx is done, FromBlock and ToBlock are not supported
+func (ec *EthClient) SubscribeLogs(ctx context.Context, filter ethereum.FilterOption) (<-chan types.Log, error) {
+	if ec.ethSub == nil {
+		return nil, ErrNoSubscriptions
+	}
+	return ec.ethSub.Logs(ctx, filter)
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"crypto-trade-client/common/web"
+	"crypto-trade-client/common/web/middleware"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEthClient_TransferTraceID(t *testing.T) {
+	node := newFakeNode(t)
+	node.mine()
+	node.handle("eth_estimateGas", func(params []json.RawMessage) (interface{}, error) {
+		return "0xfde8", nil
+	})
+	client, from := newSendingClient(t, node)
+	to := common.HexToAddress("0xebdBa70B23edf9A69B7872b5Ff9A6Ba55e2F2FE4")
+	token := common.HexToAddress("0xc2132D05D31c914a87C6611C10748AEb04B58e8F")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.HcLogger(false, hclog.NewNullLogger()))
+	router.POST("/payouts/ether", func(c *gin.Context) {
+		hash, err := client.TransferContext(c.Request.Context(), from, to, big.NewInt(1000))
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, hash.Hex())
+	})
+	router.POST("/payouts/token", func(c *gin.Context) {
+		hash, err := client.TransferTokenContext(c.Request.Context(), from, token, to, big.NewInt(1000))
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, hash.Hex())
+	})
+
+	payout := func(path, traceID string) {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set(web.HdrRequestID, traceID)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// the calls of the rpc client and of go-ethereum's client carry the trace id of the payout request
+	payout("/payouts/ether", "payout-1")
+	for _, method := range []string{"eth_getTransactionCount", "eth_chainId", "eth_gasPrice", "eth_sendRawTransaction"} {
+		assert.Equal(t, "payout-1", node.traceOf(method), method)
+	}
+
+	payout("/payouts/token", "payout-2")
+	for _, method := range []string{"eth_estimateGas", "eth_chainId", "eth_gasPrice", "eth_sendRawTransaction"} {
+		assert.Equal(t, "payout-2", node.traceOf(method), method)
+	}
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"strings"
+)
+
+// TxSigner signs the transactions of one address.
+// EvmSigner signs in-process, RemoteSigner delegates to a signing service so that the key
+// never lives in the sending process.
+type TxSigner interface {
+	// Address returns the address of the transactions signed by the signer
+	Address() common.Address
+	// SignTx returns the transaction signed for the chain
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// EvmSigner is an in-process TxSigner holding the private key
+type EvmSigner struct {
+	PrivateKey    *ecdsa.PrivateKey
+	PublicAddress common.Address
+}
+
+// NewEvmSigner creates the signer of the private key
+func NewEvmSigner(privateKey *ecdsa.PrivateKey) *EvmSigner {
+	return &EvmSigner{
+		PrivateKey:    privateKey,
+		PublicAddress: crypto.PubkeyToAddress(privateKey.PublicKey),
+	}
+}
+
+// NewEvmSignerFromHex creates the signer of a hex encoded private key
+func NewEvmSignerFromHex(privateKeyHex string) (*EvmSigner, error) {
+	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(privateKeyHex, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	return NewEvmSigner(privateKey), nil
+}
+
+func (s *EvmSigner) Address() common.Address {
+	return s.PublicAddress
+}
+
+func (s *EvmSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, txSignerFor(tx, chainID), s.PrivateKey)
+}
+
+// txSignerFor returns the go-ethereum signer matching the transaction type
+func txSignerFor(tx *types.Transaction, chainID *big.Int) types.Signer {
+	switch tx.Type() {
+	case types.LegacyTxType:
+		return types.NewEIP155Signer(chainID)
+	case types.DynamicFeeTxType:
+		return types.NewLondonSigner(chainID)
+	default:
+		return types.LatestSignerForChainID(chainID)
+	}
+}
@@ -1,6 +1,7 @@
 package ethereum
 
 import (
+	"context"
 	"crypto-trade-client/common/rpc"
 	"encoding/json"
 	"fmt"
@@ -9,21 +10,27 @@ import (
 )
 
 // EthRpc is eth namespace rpc endpoints
+//
+// Blocks by hash never change and are cached. Blocks by number and receipts are not,
+// a reorg replaces them. The fees are cached for a second.
 type EthRpc struct {
 	ProtocolVersion       func() (string, error)
 	BlockNumber           func() (hexutil.Uint64, error)
-	GetBlockByNumber      func(EthBlockNumArg, bool) (Block, error)
-	GetBlockByHash        func(string, bool) (Block, error)
+	GetBlockByNumber      func(context.Context, EthBlockNumArg, bool) (Block, error)
+	GetBlockByHash        func(string, bool) (Block, error) `cache:"immutable,size:256"`
 	GetTransactionReceipt func(string) (Receipt, error)
 	GetLogs               func(FilterOption) ([]ethcoretypes.Log, error)
 	GetBalance            func(string, EthBlockNumArg) (*hexutil.Big, error)
-	Call                  func(CallOption, EthBlockNumArg) (interface{}, error)
-	GasPrice              func() (*hexutil.Big, error) // after London will return the exact same number based on the total fees paid (tip + base)
-	MaxPriorityFeePerGas  func() (*hexutil.Big, error) // geth only, eth_maxPriorityFeePerGas after London will effectively return eth_gasPrice - baseFee
-	GetTransactionCount   func(string, EthBlockNumArg) (hexutil.Uint64, error)
+	Call                  func(context.Context, CallOption, EthBlockNumArg) (hexutil.Bytes, error)
+	GasPrice              func() (*hexutil.Big, error)                `cache:"ttl:1s"` // after London will return the exact same number based on the total fees paid (tip + base)
+	MaxPriorityFeePerGas  func(context.Context) (*hexutil.Big, error) `cache:"ttl:1s"` // geth only, eth_maxPriorityFeePerGas after London will effectively return eth_gasPrice - baseFee
+	GetTransactionCount   func(context.Context, string, EthBlockNumArg) (hexutil.Uint64, error)
 	SendRawTransaction    func(hexutil.Bytes) (hexutil.Bytes, error)
-	EstimateGas           func(CallOption, EthBlockNumArg) (hexutil.Uint64, error)
-	ChainId               func() (hexutil.Uint64, error)
+	EstimateGas           func(context.Context, CallOption, EthBlockNumArg) (hexutil.Uint64, error)
+	ChainId               func() (hexutil.Uint64, error) `cache:"ttl:1m"`
+
+	// NewBatch creates a batch of the calls above, sent in one request
+	NewBatch rpc.BatchFactory
 }
 
 func (r *EthRpc) MethodNamingConvention() rpc.NamingConvention {
@@ -38,6 +45,25 @@ func (r *EthRpc) NamespaceSeparator() string {
 	return "_"
 }
 
+// EthSubscription is eth namespace pub/sub endpoints, it needs a websocket client, see rpc.NewWebSocketClient
+type EthSubscription struct {
+	NewHeads               func(context.Context) (<-chan Block, error) // blocks without transactions
+	Logs                   func(context.Context, FilterOption) (<-chan ethcoretypes.Log, error)
+	NewPendingTransactions func(context.Context) (<-chan string, error) // transaction hashes
+}
+
+func (r *EthSubscription) MethodNamingConvention() rpc.NamingConvention {
+	return rpc.CamelCase
+}
+
+func (r *EthSubscription) Namespace() string {
+	return "eth"
+}
+
+func (r *EthSubscription) NamespaceSeparator() string {
+	return "_"
+}
+
 type FilterOption struct {
 	FromBlock EthBlockNumArg `json:"fromBlock,omitempty"`
 	ToBlock   EthBlockNumArg `json:"toBlock,omitempty"`
@@ -46,10 +72,10 @@ type FilterOption struct {
 }
 
 type CallOption struct {
-	From  string         `json:"from,omitempty"`
-	To    string         `json:"to"` // 20 Bytes - The address the transaction is directed to.
-	Value hexutil.Uint64 `json:"value,omitempty"`
-	Data  string         `json:"data"` // (optional) Hash of the method signature and encoded parameters. For details see Ethereum ContractAddr ABI in the Solidity documentation
+	From  string       `json:"from,omitempty"`
+	To    string       `json:"to"` // 20 Bytes - The address the transaction is directed to.
+	Value *hexutil.Big `json:"value,omitempty"`
+	Data  string       `json:"data"` // (optional) Hash of the method signature and encoded parameters. For details see Ethereum ContractAddr ABI in the Solidity documentation
 }
 
 type EthBlockNumArg int64
@@ -57,16 +83,24 @@ type EthBlockNumArg int64
 const (
 	Latest  EthBlockNumArg = 0
 	Pending EthBlockNumArg = -1
+	// Finalized is the latest block finalized by the consensus, nodes without finality reject it
+	Finalized EthBlockNumArg = -2
+	// Safe is the latest block unlikely to be reorged, nodes without finality reject it
+	Safe EthBlockNumArg = -3
 )
 
 func (arg EthBlockNumArg) MarshalJSON() ([]byte, error) {
-	if arg == Latest {
+	switch arg {
+	case Latest:
 		return json.Marshal("latest")
-	}
-	if arg == Pending {
+	case Pending:
 		return json.Marshal("pending")
+	case Finalized:
+		return json.Marshal("finalized")
+	case Safe:
+		return json.Marshal("safe")
 	}
-	if arg < -1 {
+	if arg < 0 {
 		return nil, fmt.Errorf("incorrect block number argument %v", arg)
 	}
 
@@ -74,12 +108,23 @@ func (arg EthBlockNumArg) MarshalJSON() ([]byte, error) {
 }
 
 func (arg *EthBlockNumArg) UnmarshalJSON(bytes []byte) error {
-	data := string(bytes)
-	if data == "latest" {
-		*arg = Latest
+	var data string
+	if err := json.Unmarshal(bytes, &data); err != nil {
+		return err
 	}
-	if data == "pending" {
+	switch data {
+	case "latest":
+		*arg = Latest
+		return nil
+	case "pending":
 		*arg = Pending
+		return nil
+	case "finalized":
+		*arg = Finalized
+		return nil
+	case "safe":
+		*arg = Safe
+		return nil
 	}
 	v, err := hexutil.DecodeUint64(data)
 	if err != nil {
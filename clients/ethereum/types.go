@@ -92,6 +92,10 @@ type Receipt struct {
 	L1FeeScalar string       `json:"l1FeeScalar"`
 	L1GasPrice  *hexutil.Big `json:"l1GasPrice"`
 	L1GasUsed   *hexutil.Big `json:"l1GasUsed"`
+
+	// extra fields for arbitrum
+	GasUsedForL1  *hexutil.Big `json:"gasUsedForL1"`
+	L1BlockNumber *hexutil.Big `json:"l1BlockNumber"`
 }
 
 type Block struct {
@@ -103,6 +107,8 @@ type Block struct {
 	Size       hexutil.Uint64 `json:"size"`       // integer the size of this block in bytes.
 	Nonce      string         `json:"nonce"`      // 8 Bytes - hash of the generated proof-of-work. null when its pending block.
 
+	BaseFeePerGas *hexutil.Big `json:"baseFeePerGas,omitempty"` // base fee of the block after London, null on chains without EIP-1559
+
 	Transactions []Transaction `json:"transactions"`
 }
 
@@ -188,4 +194,11 @@ var chains = []EvmChainInfo{
 		ShortName: "oeth",
 		ChainId:   10,
 	},
+	{
+		Name:      "Arbitrum One",
+		Chain:     "ETH",
+		Icon:      "arbitrum",
+		ShortName: "arb1",
+		ChainId:   42161,
+	},
 }
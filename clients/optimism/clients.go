@@ -2,6 +2,7 @@ package optimism
 
 import (
 	ethclient "crypto-trade-client/clients/ethereum/client"
+	"net/http"
 )
 
 type OpClient struct {
@@ -10,7 +11,12 @@ type OpClient struct {
 
 // NewOpClient creates a new Optimism client with the given endpoint and private key
 func NewOpClient(endpoint string, privateKey string) (*OpClient, error) {
-	client, err := ethclient.NewEthClient(endpoint, "optimism", privateKey)
+	return NewOpClientWithHTTPClient(endpoint, privateKey, nil)
+}
+
+// NewOpClientWithHTTPClient creates a Optimism client sending its requests with httpClient, see ethclient.NewEthClientWithHTTPClient
+func NewOpClientWithHTTPClient(endpoint string, privateKey string, httpClient *http.Client) (*OpClient, error) {
+	client, err := ethclient.NewEthClientWithHTTPClient(endpoint, "optimism", privateKey, httpClient)
 	if err != nil {
 		return nil, err
 	}
@@ -3,6 +3,7 @@ package optimism
 import (
 	"context"
 	"crypto-trade-client/common/config"
+	"crypto-trade-client/common/web/fetch/fetchtest"
 	"github.com/ethereum/go-ethereum/common"
 	. "github.com/smartystreets/goconvey/convey"
 	"math/big"
@@ -15,9 +16,10 @@ var (
 )
 
 func TestOptimismClient_GetBlock(t *testing.T) {
+	recorder := fetchtest.Replay(t)
 	Convey("Test GetBlock", t, func() {
 		endpoint := endpointMainnet
-		client, err := NewOpClient(endpoint, "")
+		client, err := NewOpClientWithHTTPClient(endpoint, "", recorder.HTTPClient())
 		So(err, ShouldBeNil)
 
 		blockHeight := 120167305
@@ -28,9 +30,10 @@ func TestOptimismClient_GetBlock(t *testing.T) {
 }
 
 func TestOptimismClient_GetLatestBlockHeight(t *testing.T) {
+	recorder := fetchtest.Replay(t)
 	Convey("Test GetLatestBlockHeight", t, func() {
 		endpoint := endpointMainnet
-		client, err := NewOpClient(endpoint, "")
+		client, err := NewOpClientWithHTTPClient(endpoint, "", recorder.HTTPClient())
 		So(err, ShouldBeNil)
 
 		height, err := client.GetLatestBlockHeight()
@@ -40,9 +43,10 @@ func TestOptimismClient_GetLatestBlockHeight(t *testing.T) {
 }
 
 func TestOptimismClient_PendingNonceAt(t *testing.T) {
+	recorder := fetchtest.Replay(t)
 	Convey("Test GetTransactionCount", t, func() {
 		endpoint := endpointTestnet
-		client, err := NewOpClient(endpoint, "")
+		client, err := NewOpClientWithHTTPClient(endpoint, "", recorder.HTTPClient())
 		So(err, ShouldBeNil)
 
 		// Testnet address for optimism
@@ -58,6 +62,9 @@ func TestOptimismClient_PendingNonceAt(t *testing.T) {
 }
 
 func TestOptimismClient_Transfer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("sends a transaction on the testnet")
+	}
 	Convey("Test Transfer", t, func() {
 		configPath := "../../../configs/chain.yaml"
 		chainConfig, err := config.LoadConfig(configPath)
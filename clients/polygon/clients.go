@@ -2,6 +2,7 @@ package polygon
 
 import (
 	ethclient "crypto-trade-client/clients/ethereum/client"
+	"net/http"
 )
 
 type PolyClient struct {
@@ -10,7 +11,12 @@ type PolyClient struct {
 
 // NewPolyClient creates a new Polygon client with the given endpoint and private key
 func NewPolyClient(endpoint string, privateKey string) (*PolyClient, error) {
-	client, err := ethclient.NewEthClient(endpoint, "core", privateKey)
+	return NewPolyClientWithHTTPClient(endpoint, privateKey, nil)
+}
+
+// NewPolyClientWithHTTPClient creates a Polygon client sending its requests with httpClient, see ethclient.NewEthClientWithHTTPClient
+func NewPolyClientWithHTTPClient(endpoint string, privateKey string, httpClient *http.Client) (*PolyClient, error) {
+	client, err := ethclient.NewEthClientWithHTTPClient(endpoint, "core", privateKey, httpClient)
 	if err != nil {
 		return nil, err
 	}
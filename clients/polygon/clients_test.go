@@ -1,6 +1,7 @@
 package polygon
 
 import (
+	"crypto-trade-client/common/web/fetch/fetchtest"
 	. "github.com/smartystreets/goconvey/convey"
 	"testing"
 )
@@ -10,9 +11,10 @@ var (
 )
 
 func TestPolyClient_GetBlock(t *testing.T) {
+	recorder := fetchtest.Replay(t)
 	Convey("Test GetBlock", t, func() {
 		endpoint := endpointMainnet
-		client, err := NewPolyClient(endpoint, "")
+		client, err := NewPolyClientWithHTTPClient(endpoint, "", recorder.HTTPClient())
 		So(err, ShouldBeNil)
 
 		blockHeight := 60019878
@@ -23,9 +25,10 @@ func TestPolyClient_GetBlock(t *testing.T) {
 }
 
 func TestPolyClient_GetLatestBlockHeight(t *testing.T) {
+	recorder := fetchtest.Replay(t)
 	Convey("Test GetLatestBlockHeight", t, func() {
 		endpoint := endpointMainnet
-		client, err := NewPolyClient(endpoint, "")
+		client, err := NewPolyClientWithHTTPClient(endpoint, "", recorder.HTTPClient())
 		So(err, ShouldBeNil)
 
 		height, err := client.GetLatestBlockHeight()
@@ -0,0 +1,62 @@
+package ripple
+
+import (
+	"context"
+	"crypto-trade-client/common/scanner"
+	"fmt"
+	"time"
+)
+
+// rippleEpoch is the unix time of the Ripple Epoch, 2000-01-01 00:00 UTC
+const rippleEpoch = 946684800
+
+// BlockSource is the scanner.BlockSource of the XRP Ledger, a block is a ledger. The ledgers only list
+// the hashes of their transactions, the transactions are fetched one by one, as *TxResp.
+type BlockSource struct {
+	client *XrpClient
+}
+
+// NewBlockSource creates the block source of the client's ledgers
+func NewBlockSource(client *XrpClient) *BlockSource {
+	return &BlockSource{client: client}
+}
+
+func (s *BlockSource) LatestHeight(ctx context.Context) (int64, error) {
+	resp, err := s.client.LedgerClosed(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int64(resp.Result.LedgerIndex), nil
+}
+
+// FinalizedHeight returns the index of the latest validated ledger
+func (s *BlockSource) FinalizedHeight(ctx context.Context) (int64, error) {
+	return s.client.ValidatedLedgerIndex(ctx)
+}
+
+func (s *BlockSource) BlockByHeight(ctx context.Context, height int64) (*scanner.Block, error) {
+	ledger, err := s.client.Ledger(ctx, "", height)
+	if err != nil {
+		return nil, err
+	}
+	if !ledger.Result.Ledger.Closed {
+		return nil, scanner.ErrBlockNotFound
+	}
+
+	block := &scanner.Block{
+		Height:       int64(ledger.Result.LedgerIndex),
+		Hash:         ledger.Result.LedgerHash,
+		ParentHash:   ledger.Result.Ledger.ParentHash,
+		Time:         time.Unix(ledger.Result.Ledger.CloseTime+rippleEpoch, 0),
+		Transactions: make([]*scanner.Transaction, len(ledger.Result.Ledger.Transactions)),
+		Raw:          ledger,
+	}
+	for i, hash := range ledger.Result.Ledger.Transactions {
+		tx, err := s.client.Tx(ctx, hash)
+		if err != nil {
+			return nil, fmt.Errorf("getting transaction %s: %w", hash, err)
+		}
+		block.Transactions[i] = &scanner.Transaction{Hash: hash, Raw: tx}
+	}
+	return block, nil
+}
@@ -0,0 +1,72 @@
+package ripple
+
+import (
+	"context"
+	"crypto-trade-client/common/web"
+	"crypto-trade-client/common/web/fetch"
+	"encoding/json"
+	"github.com/hashicorp/go-hclog"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// rippledStub answers ledger and tx with one closed ledger of one transaction,
+// it keeps the X-Request-Id of the requests by method
+type rippledStub struct {
+	lock   sync.Mutex
+	traces map[string]string
+}
+
+func (s *rippledStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Method string `json:"method"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	s.lock.Lock()
+	s.traces[req.Method] = r.Header.Get(web.HdrRequestID)
+	s.lock.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch req.Method {
+	case "ledger":
+		_, _ = w.Write([]byte(`{"result":{"ledger":{"closed":true,"close_time":700000000,"parent_hash":"AA","transactions":["T1"]},"ledger_hash":"BB","ledger_index":12,"status":"success","validated":true}}`))
+	case "tx":
+		_, _ = w.Write([]byte(`{"result":{"hash":"T1","status":"success","validated":true}}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *rippledStub) traceOf(method string) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.traces[method]
+}
+
+func TestBlockSource_Context(t *testing.T) {
+	stub := &rippledStub{traces: make(map[string]string)}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	l := hclog.NewNullLogger()
+	source := NewBlockSource(&XrpClient{NewXrpRpcWithFetch(fetch.NewClientWithEndpoint(server.URL, l), l)})
+
+	Convey("Test the requests of the block source carry its context", t, func() {
+		block, err := source.BlockByHeight(web.WithTraceID(context.Background(), "scan-1"), 12)
+		So(err, ShouldBeNil)
+		So(block.Hash, ShouldEqual, "BB")
+		So(block.Transactions, ShouldHaveLength, 1)
+		So(stub.traceOf("ledger"), ShouldEqual, "scan-1")
+		So(stub.traceOf("tx"), ShouldEqual, "scan-1")
+
+		// cancelling the scanner aborts its requests
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = source.BlockByHeight(ctx, 12)
+		So(err, ShouldWrap, context.Canceled)
+		_, err = source.FinalizedHeight(ctx)
+		So(err, ShouldWrap, context.Canceled)
+	})
+}
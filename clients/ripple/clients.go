@@ -1,6 +1,17 @@
 package ripple
 
-import "github.com/hashicorp/go-hclog"
+import (
+	"crypto-trade-client/common/config"
+	"crypto-trade-client/common/web/fetch"
+	"github.com/hashicorp/go-hclog"
+	"time"
+)
+
+const (
+	// breakerMaxFailures and breakerCooldown stop hammering a provider which is failing or rate limiting us
+	breakerMaxFailures = 5
+	breakerCooldown    = 30 * time.Second
+)
 
 type XrpClient struct {
 	*XrpRpc
@@ -18,3 +29,19 @@ func NewXrpClient(endpoint string) (*XrpClient, error) {
 
 	return &XrpClient{client}, nil
 }
+
+// NewXrpClientForChain creates a Ripple client of the chain's URL, which stays within the chain's rate limit
+// and stops sending requests for a while when the provider keeps failing
+func NewXrpClientForChain(chain config.Chain) *XrpClient {
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name: "ripple-client",
+	})
+	fetchClient := fetch.NewClientBuilder().
+		WithEndpoint(chain.URL).
+		WithLogger(logger).
+		WithRateLimit(chain.RateLimit, chain.RateBurst).
+		WithCircuitBreaker(breakerMaxFailures, breakerCooldown).
+		BuildRetryable()
+
+	return &XrpClient{NewXrpRpcWithFetch(fetchClient, logger)}
+}
@@ -19,6 +19,8 @@ type LedgerResp struct {
 			// This is like the way the Unix epoch  works, except the Ripple Epoch is 946684800 seconds after the Unix Epoch.
 			// Https://xrpl.org/basic-data-types.html#specifying-time
 			CloseTime int64 `json:"close_time"`
+			// ParentHash is the hash of the previous ledger
+			ParentHash string `json:"parent_hash"`
 			//LedgerData   string   `json:"ledger_data"`
 			Transactions []string `json:"transactions"`
 		} `json:"ledger"`
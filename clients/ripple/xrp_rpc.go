@@ -1,6 +1,7 @@
 package ripple
 
 import (
+	"context"
 	"crypto-trade-client/common/web/fetch"
 	"encoding/json"
 	"errors"
@@ -10,18 +11,24 @@ import (
 
 type XrpRpc struct {
 	logger hclog.Logger
-	client *fetch.Client
+	client fetch.ClientInterface
 }
 
 func NewXrpRpc(rpcEndpoint string, l hclog.Logger) (*XrpRpc, error) {
+	return NewXrpRpcWithFetch(fetch.NewClientWithEndpoint(rpcEndpoint, l), l), nil
+}
+
+// NewXrpRpcWithFetch creates the rpc over the fetch client, whose endpoint is the rippled JSON-RPC URL
+func NewXrpRpcWithFetch(client fetch.ClientInterface, l hclog.Logger) *XrpRpc {
 	return &XrpRpc{
 		logger: l,
-		client: fetch.NewClientWithEndpoint(rpcEndpoint, l),
-	}, nil
+		client: client,
+	}
 }
 
-func (r *XrpRpc) LedgerClosed() (*LedgerClosedResp, error) {
+func (r *XrpRpc) LedgerClosed(ctx context.Context) (*LedgerClosedResp, error) {
 	resp, err := r.client.Post("").
+		WithContext(ctx).
 		SetHeaders(map[string]string{"Content-Type": "application/json"}).
 		SetBody(map[string]interface{}{
 			"id":     uuid.New(),
@@ -43,7 +50,7 @@ func (r *XrpRpc) LedgerClosed() (*LedgerClosedResp, error) {
 	return &p, nil
 }
 
-func (r *XrpRpc) Ledger(hash string, height int64) (*LedgerResp, error) {
+func (r *XrpRpc) Ledger(ctx context.Context, hash string, height int64) (*LedgerResp, error) {
 	params := []map[string]interface{}{
 		{
 			"id":           uuid.New(),
@@ -58,6 +65,7 @@ func (r *XrpRpc) Ledger(hash string, height int64) (*LedgerResp, error) {
 	}
 
 	resp, err := r.client.Post("").
+		WithContext(ctx).
 		SetHeaders(map[string]string{"Content-Type": "application/json"}).
 		SetBody(map[string]interface{}{
 			"method": "ledger",
@@ -78,8 +86,38 @@ func (r *XrpRpc) Ledger(hash string, height int64) (*LedgerResp, error) {
 	return &ledger, nil
 }
 
-func (r *XrpRpc) Tx(hash string) (*TxResp, error) {
+// ValidatedLedgerIndex returns the index of the latest validated ledger, whose transactions are final
+func (r *XrpRpc) ValidatedLedgerIndex(ctx context.Context) (int64, error) {
+	resp, err := r.client.Post("").
+		WithContext(ctx).
+		SetHeaders(map[string]string{"Content-Type": "application/json"}).
+		SetBody(map[string]interface{}{
+			"method": "ledger",
+			"params": []map[string]interface{}{
+				{
+					"id":           uuid.New(),
+					"ledger_index": "validated",
+				},
+			},
+		}).Execute()
+	if err != nil {
+		return 0, err
+	}
+	var ledger LedgerResp
+	err = json.Unmarshal(resp.BodyBytes(), &ledger)
+	if err != nil {
+		return 0, err
+	}
+	if ledger.Result.Status != "success" || !ledger.Result.Validated {
+		r.logger.Error("response is not valid for validated ledger", "resp", string(resp.BodyBytes()))
+		return 0, errors.New("get validated ledger failed. json-rpc response with error msg")
+	}
+	return int64(ledger.Result.LedgerIndex), nil
+}
+
+func (r *XrpRpc) Tx(ctx context.Context, hash string) (*TxResp, error) {
 	resp, err := r.client.Post("").
+		WithContext(ctx).
 		SetHeaders(map[string]string{"Content-Type": "application/json"}).
 		SetBody(map[string]interface{}{
 			"method": "tx",
@@ -100,8 +138,8 @@ func (r *XrpRpc) Tx(hash string) (*TxResp, error) {
 	return &tx, nil
 }
 
-func (r *XrpRpc) Fee() (*FeeResp, error) {
-	resp, err := r.client.Post("").SetBody(map[string]interface{}{
+func (r *XrpRpc) Fee(ctx context.Context) (*FeeResp, error) {
+	resp, err := r.client.Post("").WithContext(ctx).SetBody(map[string]interface{}{
 		"method": "fee",
 		"params": []map[string]interface{}{},
 	}).SetHeaders(map[string]string{"Content-Type": "application/json"}).Execute()
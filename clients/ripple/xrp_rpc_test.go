@@ -1,6 +1,9 @@
 package ripple
 
 import (
+	"context"
+	"crypto-trade-client/common/web/fetch"
+	"crypto-trade-client/common/web/fetch/fetchtest"
 	"github.com/hashicorp/go-hclog"
 	. "github.com/smartystreets/goconvey/convey"
 	"testing"
@@ -15,47 +18,49 @@ var (
 )
 
 func TestXrpRpc_LedgerClosed(t *testing.T) {
+	// the request carries a random id
+	recorder := fetchtest.Replay(t).IgnoreFields("id")
 	Convey("Test LedgerClosed", t, func() {
-		client, err := NewXrpRpc(rpcEndpoint, logger)
-		So(err, ShouldBeNil)
+		client := NewXrpRpcWithFetch(fetch.NewClientWithCustomHttpClient(recorder.HTTPClient(), rpcEndpoint, logger), logger)
 
-		resp, err := client.LedgerClosed()
+		resp, err := client.LedgerClosed(context.Background())
 		So(err, ShouldBeNil)
 		So(resp.Result.Status, ShouldEqual, "success")
 	})
 }
 
 func TestXrpRpc_Ledger(t *testing.T) {
+	// the ledger params carry a random id
+	recorder := fetchtest.Replay(t).IgnoreFields("id")
 	Convey("Test Ledger", t, func() {
-		client, err := NewXrpRpc(rpcEndpoint, logger)
-		So(err, ShouldBeNil)
+		client := NewXrpRpcWithFetch(fetch.NewClientWithCustomHttpClient(recorder.HTTPClient(), rpcEndpoint, logger), logger)
 
 		hash := "630FD835CD15D35418699CD80DFF0A52EBE585D676F78ECCA34A02ACB2889CCC"
 		height := int64(89443608)
-		resp, err := client.Ledger(hash, height)
+		resp, err := client.Ledger(context.Background(), hash, height)
 		So(err, ShouldBeNil)
 		So(resp.Result.Status, ShouldEqual, "success")
 	})
 }
 
 func TestXrpRpc_Tx(t *testing.T) {
+	recorder := fetchtest.Replay(t)
 	Convey("Test Tx", t, func() {
-		client, err := NewXrpRpc(rpcEndpoint, logger)
-		So(err, ShouldBeNil)
+		client := NewXrpRpcWithFetch(fetch.NewClientWithCustomHttpClient(recorder.HTTPClient(), rpcEndpoint, logger), logger)
 
 		hash := "562259FD65583C544B2B328EF3F51007E20620C90746DA32E17CC0306C0CB812"
-		resp, err := client.Tx(hash)
+		resp, err := client.Tx(context.Background(), hash)
 		So(err, ShouldBeNil)
 		So(resp.Result.Status, ShouldEqual, "success")
 	})
 }
 
 func TestXrpRpc_Fee(t *testing.T) {
+	recorder := fetchtest.Replay(t)
 	Convey("Test Fee", t, func() {
-		client, err := NewXrpRpc(rpcEndpoint, logger)
-		So(err, ShouldBeNil)
+		client := NewXrpRpcWithFetch(fetch.NewClientWithCustomHttpClient(recorder.HTTPClient(), rpcEndpoint, logger), logger)
 
-		resp, err := client.Fee()
+		resp, err := client.Fee(context.Background())
 		So(err, ShouldBeNil)
 		So(resp.Result.Status, ShouldEqual, "success")
 	})
@@ -8,6 +8,9 @@ import (
 )
 
 func TestSendTransaction(t *testing.T) {
+	if testing.Short() {
+		t.Skip("sends a transaction on the devnet")
+	}
 	Convey("Test SendTransaction", t, func() {
 		keyPath := "/Users/jeff.wu/.config/solana/id.json"
 		client, err := NewSolClient(context.Background(), keyPath)
@@ -28,6 +31,9 @@ func TestSendTransaction(t *testing.T) {
 }
 
 func TestGetBalance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("reads a local key and the devnet")
+	}
 	Convey("Test GetBalance", t, func() {
 		keyPath := "/Users/jeff.wu/.config/solana/id.json"
 		client, err := NewSolClient(context.Background(), keyPath)
@@ -42,6 +48,9 @@ func TestGetBalance(t *testing.T) {
 }
 
 func TestGetTransaction(t *testing.T) {
+	if testing.Short() {
+		t.Skip("reads a local key and the devnet")
+	}
 	Convey("Test GetTransaction", t, func() {
 		keyPath := "/Users/jeff.wu/.config/solana/id.json"
 		client, err := NewSolClient(context.Background(), keyPath)
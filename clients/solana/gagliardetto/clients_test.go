@@ -31,6 +31,9 @@ import (
 //}
 
 func TestGetBalance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("reads a local key and the devnet")
+	}
 	Convey("Test GetBalance", t, func() {
 		keyPath := "/Users/jeff.wu/.config/solana/id.json"
 		client, err := NewSolClient(context.Background(), keyPath)
@@ -45,6 +48,9 @@ func TestGetBalance(t *testing.T) {
 }
 
 func TestGetTransaction(t *testing.T) {
+	if testing.Short() {
+		t.Skip("reads a local key and the devnet")
+	}
 	Convey("Test GetTransaction", t, func() {
 		keyPath := "/Users/jeff.wu/.config/solana/id.json"
 		client, err := NewSolClient(context.Background(), keyPath)
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	ethclient "crypto-trade-client/clients/ethereum/client"
+	"crypto-trade-client/clients/ripple"
+	"crypto-trade-client/common/config"
+	"crypto-trade-client/common/metrics"
+	"crypto-trade-client/common/scanner"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// chainSource opens the block source of a chain, adding a chain means adding its entry to chains
+type chainSource struct {
+	interval time.Duration // default poll interval, about the block time of the chain
+	open     func(ctx context.Context, chain config.Chain) (scanner.BlockSource, error)
+
+	// default confirmation of the blocks, overridden by the confirmations and finalized settings of the chain
+	confirmations int64
+	finalized     bool
+}
+
+var chains = map[string]chainSource{
+	"arbitrum": {interval: time.Second, open: openEvmSource, finalized: true},
+	"core":     {interval: time.Second, open: openEvmSource, confirmations: 15},
+	"ethereum": {interval: 12 * time.Second, open: openEvmSource, finalized: true},
+	"optimism": {interval: time.Second, open: openEvmSource, finalized: true},
+	"polygon":  {interval: 2 * time.Second, open: openEvmSource, confirmations: 128},
+	"ripple":   {interval: 3 * time.Second, open: openXrpSource, finalized: true}, // the validated ledgers
+}
+
+func main() {
+	var configPath, chainName string
+	var opts scanOptions
+	var fill backfillOptions
+	var metricsAddr, metricsBackend string
+
+	var rootCmd = &cobra.Command{
+		Use:   "scanner",
+		Short: "Scanner follows the blocks of a chain",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if metricsAddr != "" {
+				if err := metrics.Serve(metricsAddr, metricsBackend, "scanner"); err != nil {
+					return fmt.Errorf("error serving metrics: %w", err)
+				}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return scan(ctx, configPath, chainName, opts)
+		},
+	}
+
+	var backfillCmd = &cobra.Command{
+		Use:   "backfill",
+		Short: "Backfill passes the blocks of a past range to the handlers, e.g. after adding a watched address",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return backfill(ctx, configPath, chainName, fill)
+		},
+	}
+
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "path to the configuration file")
+	rootCmd.PersistentFlags().StringVar(&chainName, "chain", "", "chain to scan, one of "+strings.Join(chainNames(), ", "))
+	rootCmd.PersistentFlags().StringVar(&metricsAddr, "metrics-addr", "", "address serving the request metrics, e.g. :9100")
+	rootCmd.PersistentFlags().StringVar(&metricsBackend, "metrics-backend", metrics.BackendPrometheus, "metrics backend, prometheus or expvar")
+	_ = rootCmd.MarkPersistentFlagRequired("config")
+	_ = rootCmd.MarkPersistentFlagRequired("chain")
+
+	rootCmd.Flags().DurationVar(&opts.interval, "interval", 0, "interval between two polls of the chain, default depends on the chain")
+	rootCmd.Flags().StringVar(&opts.checkpoints, "checkpoints", "scanner-checkpoints.json", "file of the scan checkpoints, the scan resumes after the last confirmed block of the chain; empty disables them")
+	rootCmd.Flags().Int64Var(&opts.fromHeight, "from-height", 0, "first height to scan, overriding the checkpoint")
+
+	backfillCmd.Flags().Int64Var(&fill.from, "from", 0, "first height of the range")
+	backfillCmd.Flags().Int64Var(&fill.to, "to", 0, "last height of the range")
+	backfillCmd.Flags().IntVar(&fill.workers, "workers", scanner.DefaultWorkers, "number of concurrent fetches, keep within the provider's rate limit")
+	backfillCmd.Flags().IntVar(&fill.batchSize, "batch-size", 1, "blocks fetched at once by a worker, in one JSON-RPC batch on the EVM chains")
+	backfillCmd.Flags().DurationVar(&fill.progressInterval, "progress-interval", scanner.DefaultProgressInterval, "interval between two progress reports")
+	_ = backfillCmd.MarkFlagRequired("from")
+	_ = backfillCmd.MarkFlagRequired("to")
+	rootCmd.AddCommand(backfillCmd)
+
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+type scanOptions struct {
+	interval    time.Duration
+	checkpoints string
+	fromHeight  int64
+}
+
+type backfillOptions struct {
+	from, to         int64
+	workers          int
+	batchSize        int
+	progressInterval time.Duration
+}
+
+func scan(ctx context.Context, configPath, chainName string, opts scanOptions) error {
+	s, err := newScanner(ctx, configPath, chainName, opts.interval)
+	if err != nil {
+		return err
+	}
+	s.WithStartHeight(opts.fromHeight)
+	if opts.checkpoints != "" {
+		s.WithCheckpoints(scanner.NewFileCheckpointStore(opts.checkpoints))
+	}
+	err = s.Run(ctx)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	if errors.Is(err, scanner.ErrReorgTooDeep) {
+		return fmt.Errorf("%w, rescan from below the fork with --from-height", err)
+	}
+	return err
+}
+
+func backfill(ctx context.Context, configPath, chainName string, opts backfillOptions) error {
+	s, err := newScanner(ctx, configPath, chainName, 0)
+	if err != nil {
+		return err
+	}
+	s.WithWorkers(opts.workers).
+		WithBatchSize(opts.batchSize).
+		WithProgressInterval(opts.progressInterval)
+	progress, err := s.Backfill(ctx, opts.from, opts.to)
+	if err != nil {
+		return fmt.Errorf("%w, resume with --from %d", err, progress.Height+1)
+	}
+	fmt.Printf("Backfilled %d blocks with %d transactions in %s\n",
+		progress.Blocks, progress.Transactions, progress.Elapsed.Round(time.Second))
+	return nil
+}
+
+// newScanner creates the scanner of the chain with the handlers of the blocks
+func newScanner(ctx context.Context, configPath, chainName string, interval time.Duration) (*scanner.Scanner, error) {
+	source, ok := chains[chainName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported chain %q, expected one of %s", chainName, strings.Join(chainNames(), ", "))
+	}
+
+	chainConfig, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading chainConfig: %w", err)
+	}
+	chain, ok := chainConfig[chainName]
+	if !ok {
+		return nil, fmt.Errorf("chain %s not found in chainConfig", chainName)
+	}
+
+	blocks, err := source.open(ctx, chain)
+	if err != nil {
+		return nil, fmt.Errorf("error creating %s client: %w", chainName, err)
+	}
+	if interval == 0 {
+		interval = source.interval
+	}
+
+	s := scanner.NewScanner(chainName, blocks).
+		WithInterval(interval).
+		OnBlock(printBlock).
+		OnTransaction(printTransaction).
+		OnRollback(printRollback).
+		OnConfirmedBlock(printConfirmedBlock)
+	switch {
+	case chain.Confirmations > 0:
+		s.WithConfirmations(chain.Confirmations)
+	case chain.Finalized || source.finalized:
+		s.WithFinality()
+	default:
+		s.WithConfirmations(source.confirmations)
+	}
+	return s, nil
+}
+
+func openEvmSource(ctx context.Context, chain config.Chain) (scanner.BlockSource, error) {
+	// scanning only reads the chain, no signer is needed
+	client, err := ethclient.NewEthClientForEndpoints(ctx, chain.AllEndpoints(), chain.Name, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.NewBlockSource(client), nil
+}
+
+func openXrpSource(ctx context.Context, chain config.Chain) (scanner.BlockSource, error) {
+	// the transactions of busy ledgers are fetched one by one, keep within the provider's rate limit
+	return ripple.NewBlockSource(ripple.NewXrpClientForChain(chain)), nil
+}
+
+func printBlock(ctx context.Context, block *scanner.Block) error {
+	fmt.Printf("Retrieved block %d with hash %s\n", block.Height, block.Hash)
+	return nil
+}
+
+func printTransaction(ctx context.Context, block *scanner.Block, tx *scanner.Transaction) error {
+	if xrpTx, ok := tx.Raw.(*ripple.TxResp); ok {
+		fmt.Printf("Retrieved transaction %s with type %s\n", tx.Hash, xrpTx.Result.TransactionType)
+	}
+	return nil
+}
+
+func printConfirmedBlock(ctx context.Context, block *scanner.Block) error {
+	fmt.Printf("Confirmed block %d with hash %s\n", block.Height, block.Hash)
+	return nil
+}
+
+func printRollback(ctx context.Context, block *scanner.Block) error {
+	fmt.Printf("Rolled back block %d with hash %s\n", block.Height, block.Hash)
+	return nil
+}
+
+func chainNames() []string {
+	names := make([]string, 0, len(chains))
+	for name := range chains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
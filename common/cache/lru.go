@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a thread-safe kv cache bounded by its number of entries.
+// The least recently used entry is evicted when a new entry does not fit. Entries saved by Save never expire,
+// which suits data which never changes once read, e.g. finalized blocks; those saved by SaveWithTTL expire,
+// and an expired entry which is not read again is evicted like any other.
+type LRU struct {
+	lock  sync.Mutex
+	size  int
+	order *list.List // front is the most recently used
+	kv    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key         string
+	data        interface{}
+	expiredTime time.Time // zero never expires
+}
+
+// NewLRU creates a cache holding at most size entries, size lower than 1 is raised to 1
+func NewLRU(size int) *LRU {
+	if size < 1 {
+		size = 1
+	}
+	return &LRU{
+		size:  size,
+		order: list.New(),
+		kv:    make(map[string]*list.Element),
+	}
+}
+
+func (c *LRU) Save(k string, v interface{}) {
+	c.save(k, v, time.Time{})
+}
+
+// SaveWithTTL saves the entry until ttl elapses
+func (c *LRU) SaveWithTTL(k string, v interface{}, ttl time.Duration) {
+	c.save(k, v, time.Now().Add(ttl))
+}
+
+func (c *LRU) save(k string, v interface{}, expiredTime time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e, ok := c.kv[k]; ok {
+		entry := e.Value.(*lruEntry)
+		entry.data = v
+		entry.expiredTime = expiredTime
+		c.order.MoveToFront(e)
+		return
+	}
+	c.kv[k] = c.order.PushFront(&lruEntry{key: k, data: v, expiredTime: expiredTime})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.kv, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (c *LRU) Get(k string) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.kv[k]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*lruEntry)
+	if !entry.expiredTime.IsZero() && entry.expiredTime.Before(time.Now()) {
+		c.order.Remove(e)
+		delete(c.kv, k)
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return entry.data, true
+}
+
+// Len returns the number of entries
+func (c *LRU) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.order.Len()
+}
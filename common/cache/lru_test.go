@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LRU_Eviction(t *testing.T) {
+	c := NewLRU(3)
+	c.Save("a", 1)
+	c.Save("b", 2)
+	c.Save("c", 3)
+
+	// reading a makes b the least recently used
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	c.Save("d", 4)
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+
+	// saving an existing key updates it without evicting
+	c.Save("c", 30)
+	assert.Equal(t, 3, c.Len())
+	v, _ = c.Get("c")
+	assert.Equal(t, 30, v)
+
+	// a is now the least recently used
+	c.Save("e", 5)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	for _, k := range []string{"c", "d", "e"} {
+		_, ok = c.Get(k)
+		assert.True(t, ok, k)
+	}
+}
+
+func Test_LRU_Capacity(t *testing.T) {
+	c := NewLRU(10)
+	for i := 0; i < 100; i++ {
+		c.Save(fmt.Sprint(i), i)
+	}
+	assert.Equal(t, 10, c.Len())
+	for i := 90; i < 100; i++ {
+		v, ok := c.Get(fmt.Sprint(i))
+		assert.True(t, ok)
+		assert.Equal(t, i, v)
+	}
+
+	// the size is at least 1
+	c = NewLRU(0)
+	c.Save("a", 1)
+	c.Save("b", 2)
+	assert.Equal(t, 1, c.Len())
+	_, ok := c.Get("b")
+	assert.True(t, ok)
+}
+
+func Test_LRU_TTL(t *testing.T) {
+	c := NewLRU(3)
+	c.SaveWithTTL("a", 1, 50*time.Millisecond)
+	c.Save("b", 2)
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	time.Sleep(80 * time.Millisecond)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.Len())
+	_, ok = c.Get("b")
+	assert.True(t, ok)
+
+	// an entry saved again without ttl does not expire anymore
+	c.SaveWithTTL("c", 3, 10*time.Millisecond)
+	c.Save("c", 3)
+	time.Sleep(20 * time.Millisecond)
+	_, ok = c.Get("c")
+	assert.True(t, ok)
+}
+
+func Test_LRU_Concurrent(t *testing.T) {
+	c := NewLRU(64)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				k := fmt.Sprint((w*1000 + i) % 100)
+				if i%3 == 0 {
+					c.SaveWithTTL(k, i, time.Millisecond)
+				} else {
+					c.Save(k, i)
+				}
+				c.Get(k)
+				c.Len()
+			}
+		}(w)
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, c.Len(), 64)
+}
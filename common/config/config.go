@@ -21,11 +21,52 @@ type Config struct {
 	Chains []Chain `yaml:"Chains"`
 }
 
+// Endpoint is a node of a chain, requests are spread over the endpoints in proportion to their weights
+type Endpoint struct {
+	URL    string `yaml:"url"`
+	Weight int    `yaml:"weight"` // 0 is 1
+}
+
 // Chain represents a single chain configuration
 type Chain struct {
 	Name       string `yaml:"name"`
 	URL        string `yaml:"url"`
 	PrivateKey string `yaml:"privateKey"`
+
+	// Endpoints are the nodes of the chain for failover and load balancing, URL is used when it is empty
+	Endpoints []Endpoint `yaml:"endpoints"`
+	// RateLimit is the requests per second allowed by the provider's plan, 0 is no limit
+	RateLimit float64 `yaml:"rateLimit"`
+	// RateBurst is the number of requests which may be sent at once within RateLimit, default is 1
+	RateBurst int `yaml:"rateBurst"`
+
+	// Confirmations is the number of confirmations of a confirmed block, the head has 1, 0 is the chain's default
+	Confirmations int64 `yaml:"confirmations"`
+	// Finalized confirms the blocks once the chain finalizes them, e.g. the finalized tag of Ethereum,
+	// rather than after Confirmations
+	Finalized bool `yaml:"finalized"`
+
+	// Keystore is the path of a V3 keystore file, it is preferred over PrivateKey
+	Keystore string `yaml:"keystore"`
+	// MnemonicFile is the path of a file containing a BIP-39 mnemonic
+	MnemonicFile string `yaml:"mnemonicFile"`
+	// DerivationPath is the BIP-44 path used with MnemonicFile, default is m/44'/60'/0'/0/0
+	DerivationPath string `yaml:"derivationPath"`
+	// PasswordEnv is the environment variable holding the keystore password or the mnemonic passphrase
+	PasswordEnv string `yaml:"passwordEnv"`
+	// PasswordFile is the path of a file holding the keystore password
+	PasswordFile string `yaml:"passwordFile"`
+}
+
+// AllEndpoints returns the endpoints of the chain, URL alone when no endpoints are configured
+func (c Chain) AllEndpoints() []Endpoint {
+	if len(c.Endpoints) > 0 {
+		return c.Endpoints
+	}
+	if c.URL == "" {
+		return nil
+	}
+	return []Endpoint{{URL: c.URL, Weight: 1}}
 }
 
 func LoadConfig(configPath string) (map[string]Chain, error) {
@@ -17,6 +17,15 @@ Chains:
     url: "https://tiniest-wandering-flower.solana-mainnet.quiknode.pro/3f2cf77b66958c08189f7d289df7d0740e554be2"
   - name: "optimism"
     url: "https://practical-green-butterfly.optimism.quiknode.pro/d02f8d49bde8ccbbcec3c9a8962646db998ade83"
+  - name: "polygon"
+    endpoints:
+      - url: "https://polygon-rpc.com"
+        weight: 3
+      - url: "https://rpc.ankr.com/polygon"
+    confirmations: 128
+  - name: "ripple"
+    url: "https://s1.ripple.com:51234"
+    finalized: true
 `
 		tmpFile, err := os.CreateTemp("", "config.yaml")
 		So(err, ShouldBeNil)
@@ -42,5 +51,17 @@ Chains:
 
 		So(chainMap, ShouldContainKey, "optimism")
 		So(chainMap["optimism"].URL, ShouldEqual, "https://practical-green-butterfly.optimism.quiknode.pro/d02f8d49bde8ccbbcec3c9a8962646db998ade83")
+		So(chainMap["optimism"].AllEndpoints(), ShouldResemble, []Endpoint{{URL: chainMap["optimism"].URL, Weight: 1}})
+
+		So(chainMap, ShouldContainKey, "polygon")
+		So(chainMap["polygon"].AllEndpoints(), ShouldResemble, []Endpoint{
+			{URL: "https://polygon-rpc.com", Weight: 3},
+			{URL: "https://rpc.ankr.com/polygon"},
+		})
+		So(chainMap["polygon"].Confirmations, ShouldEqual, 128)
+		So(chainMap["polygon"].Finalized, ShouldBeFalse)
+
+		So(chainMap, ShouldContainKey, "ripple")
+		So(chainMap["ripple"].Finalized, ShouldBeTrue)
 	})
 }
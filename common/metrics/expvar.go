@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"crypto-trade-client/common/rpc"
+	"crypto-trade-client/common/web/fetch"
+	"expvar"
+	"strings"
+)
+
+// Expvar publishes the metrics of the clients with expvar, served as JSON at /debug/vars by
+// the default HTTP mux. It needs no metrics backend:
+//
+//	{"<name>": {
+//		"http_requests":    {"<host> <method> <status>": count},
+//		"http_retries":     {"<host> <method>": count},
+//		"http_duration_ms": {"<host> <method>": total milliseconds},
+//		"rpc_calls":        {"<client> <method> <code>": count},
+//		"rpc_duration_ms":  {"<client> <method>": total milliseconds}
+//	}}
+type Expvar struct {
+	httpRequests *expvar.Map
+	httpRetries  *expvar.Map
+	httpDuration *expvar.Map
+	rpcCalls     *expvar.Map
+	rpcDuration  *expvar.Map
+}
+
+// NewExpvar publishes the metrics under the name, the variable is reused when it is already published
+func NewExpvar(name string) *Expvar {
+	root, ok := expvar.Get(name).(*expvar.Map)
+	if !ok {
+		root = expvar.NewMap(name)
+	}
+	child := func(key string) *expvar.Map {
+		if m, ok := root.Get(key).(*expvar.Map); ok {
+			return m
+		}
+		m := new(expvar.Map).Init()
+		root.Set(key, m)
+		return m
+	}
+	return &Expvar{
+		httpRequests: child("http_requests"),
+		httpRetries:  child("http_retries"),
+		httpDuration: child("http_duration_ms"),
+		rpcCalls:     child("rpc_calls"),
+		rpcDuration:  child("rpc_duration_ms"),
+	}
+}
+
+func (e *Expvar) ObserveRequest(m fetch.RequestMetrics) {
+	e.httpRequests.Add(key(m.Host, m.Method, statusLabel(m)), 1)
+	if m.Attempt > 1 {
+		e.httpRetries.Add(key(m.Host, m.Method), 1)
+	}
+	e.httpDuration.AddFloat(key(m.Host, m.Method), float64(m.Duration.Microseconds())/1000)
+}
+
+func (e *Expvar) ObserveCall(m rpc.CallMetrics) {
+	e.rpcCalls.Add(key(m.Client, m.Method, codeLabel(m)), 1)
+	e.rpcDuration.AddFloat(key(m.Client, m.Method), float64(m.Duration.Microseconds())/1000)
+}
+
+func key(labels ...string) string {
+	return strings.Join(labels, " ")
+}
@@ -0,0 +1,54 @@
+// Package metrics exports the metrics of the fetch and rpc clients, to Prometheus or to expvar.
+//
+//	m, err := metrics.NewPrometheus(prometheus.DefaultRegisterer, "scanner")
+//	if err != nil {
+//		return err
+//	}
+//	metrics.Install(m)
+//	http.Handle("/metrics", promhttp.Handler())
+package metrics
+
+import (
+	"crypto-trade-client/common/rpc"
+	"crypto-trade-client/common/web/fetch"
+	"strconv"
+)
+
+// Hooks observes both the HTTP requests and the JSON-RPC calls
+type Hooks interface {
+	fetch.MetricsHook
+	rpc.MetricsHook
+}
+
+// Install makes the hooks observe the requests of all the fetch clients and the calls of all the rpc clients,
+// except those with their own hook, see fetch.Client.WithMetricsHook and rpc.MetricsHook
+func Install(hooks Hooks) {
+	fetch.SetMetricsHook(hooks)
+	rpc.SetMetricsHook(hooks)
+}
+
+// Uninstall stops observing the clients
+func Uninstall() {
+	fetch.SetMetricsHook(nil)
+	rpc.SetMetricsHook(nil)
+}
+
+// statusLabel is the HTTP status, or "error" when no response was received
+func statusLabel(m fetch.RequestMetrics) string {
+	if m.Status == 0 {
+		return "error"
+	}
+	return strconv.Itoa(m.Status)
+}
+
+// codeLabel is "ok", the JSON-RPC error code, or "error" for failures without a code, e.g. transport errors
+func codeLabel(m rpc.CallMetrics) string {
+	switch {
+	case m.Err == nil:
+		return "ok"
+	case m.Code != 0:
+		return strconv.FormatInt(m.Code, 10)
+	default:
+		return "error"
+	}
+}
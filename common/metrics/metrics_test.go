@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"crypto-trade-client/common/rpc"
+	"crypto-trade-client/common/web/fetch"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Prometheus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	p, err := NewPrometheus(reg, "test")
+	require.NoError(t, err)
+
+	p.ObserveRequest(fetch.RequestMetrics{Host: "a.example", Method: "POST", Status: 503, Attempt: 1, Duration: time.Millisecond})
+	p.ObserveRequest(fetch.RequestMetrics{Host: "a.example", Method: "POST", Status: 200, Attempt: 2, Duration: time.Millisecond})
+	p.ObserveRequest(fetch.RequestMetrics{Host: "b.example", Method: "POST", Attempt: 1, Err: errors.New("refused")})
+	p.ObserveCall(rpc.CallMetrics{Client: "eth", Method: "eth_blockNumber"})
+	p.ObserveCall(rpc.CallMetrics{Client: "eth", Method: "eth_call", Code: -32000, Err: errors.New("reverted")})
+	p.ObserveCall(rpc.CallMetrics{Client: "eth", Method: "eth_call", Err: errors.New("timeout")})
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.httpRequests.WithLabelValues("a.example", "POST", "503")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.httpRequests.WithLabelValues("a.example", "POST", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.httpRequests.WithLabelValues("b.example", "POST", "error")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.httpRetries.WithLabelValues("a.example", "POST")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.rpcCalls.WithLabelValues("eth", "eth_blockNumber", "ok")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.rpcCalls.WithLabelValues("eth", "eth_call", "-32000")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.rpcCalls.WithLabelValues("eth", "eth_call", "error")))
+
+	// the collectors of a namespace are registered once
+	_, err = NewPrometheus(reg, "test")
+	assert.Error(t, err)
+}
+
+func Test_Expvar(t *testing.T) {
+	e := NewExpvar("metrics_test")
+	e.ObserveRequest(fetch.RequestMetrics{Host: "a.example", Method: "GET", Status: 200, Attempt: 2, Duration: 2 * time.Millisecond})
+	e.ObserveCall(rpc.CallMetrics{Client: "xrp", Method: "ledger", Code: -32603, Err: errors.New("failed")})
+
+	// publishing the name again reuses the variable
+	NewExpvar("metrics_test").ObserveRequest(fetch.RequestMetrics{Host: "a.example", Method: "GET", Status: 200, Attempt: 1})
+
+	var vars struct {
+		HTTPRequests map[string]int64   `json:"http_requests"`
+		HTTPRetries  map[string]int64   `json:"http_retries"`
+		HTTPDuration map[string]float64 `json:"http_duration_ms"`
+		RPCCalls     map[string]int64   `json:"rpc_calls"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("metrics_test").String()), &vars))
+	assert.Equal(t, int64(2), vars.HTTPRequests["a.example GET 200"])
+	assert.Equal(t, int64(1), vars.HTTPRetries["a.example GET"])
+	assert.Equal(t, 2.0, vars.HTTPDuration["a.example GET"])
+	assert.Equal(t, int64(1), vars.RPCCalls["xrp ledger -32603"])
+}
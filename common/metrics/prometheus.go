@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"crypto-trade-client/common/rpc"
+	"crypto-trade-client/common/web/fetch"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prometheus exposes the metrics of the clients as Prometheus collectors:
+//
+//	<namespace>_http_requests_total{host, method, status}
+//	<namespace>_http_retries_total{host, method}              attempts after the first one
+//	<namespace>_http_request_duration_seconds{host, method}
+//	<namespace>_rpc_calls_total{client, method, code}         code is ok, a JSON-RPC error code or error
+//	<namespace>_rpc_call_duration_seconds{client, method}
+type Prometheus struct {
+	httpRequests *prometheus.CounterVec
+	httpRetries  *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	rpcCalls     *prometheus.CounterVec
+	rpcDuration  *prometheus.HistogramVec
+}
+
+// NewPrometheus creates the collectors of the namespace and registers them
+func NewPrometheus(registerer prometheus.Registerer, namespace string) (*Prometheus, error) {
+	p := &Prometheus{
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests sent, by host, method and response status.",
+		}, []string{"host", "method", "status"}),
+		httpRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_retries_total",
+			Help:      "HTTP requests sent again after a failed attempt, by host and method.",
+		}, []string{"host", "method"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of the HTTP requests, by host and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"host", "method"}),
+		rpcCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rpc_calls_total",
+			Help:      "JSON-RPC calls sent, by client, method and error code.",
+		}, []string{"client", "method", "code"}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rpc_call_duration_seconds",
+			Help:      "Latency of the JSON-RPC calls, by client and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"client", "method"}),
+	}
+
+	for _, c := range []prometheus.Collector{p.httpRequests, p.httpRetries, p.httpDuration, p.rpcCalls, p.rpcDuration} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *Prometheus) ObserveRequest(m fetch.RequestMetrics) {
+	p.httpRequests.WithLabelValues(m.Host, m.Method, statusLabel(m)).Inc()
+	if m.Attempt > 1 {
+		p.httpRetries.WithLabelValues(m.Host, m.Method).Inc()
+	}
+	p.httpDuration.WithLabelValues(m.Host, m.Method).Observe(m.Duration.Seconds())
+}
+
+func (p *Prometheus) ObserveCall(m rpc.CallMetrics) {
+	p.rpcCalls.WithLabelValues(m.Client, m.Method, codeLabel(m)).Inc()
+	p.rpcDuration.WithLabelValues(m.Client, m.Method).Observe(m.Duration.Seconds())
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net"
+	"net/http"
+)
+
+const (
+	BackendPrometheus = "prometheus"
+	BackendExpvar     = "expvar"
+)
+
+// Serve installs the hooks of the backend and serves the metrics on addr in the background,
+// at /metrics for BackendPrometheus and at /debug/vars for BackendExpvar
+func Serve(addr, backend, namespace string) error {
+	mux := http.NewServeMux()
+	switch backend {
+	case BackendPrometheus:
+		p, err := NewPrometheus(prometheus.DefaultRegisterer, namespace)
+		if err != nil {
+			return err
+		}
+		Install(p)
+		mux.Handle("/metrics", promhttp.Handler())
+	case BackendExpvar:
+		Install(NewExpvar(namespace))
+		mux.Handle("/debug/vars", expvar.Handler())
+	default:
+		return fmt.Errorf("unknown metrics backend %q", backend)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			hclog.L().Named("metrics").Error("metrics server stopped", "addr", addr, "error", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,250 @@
+package rpc
+
+import (
+	"context"
+	"crypto-trade-client/common/rpc/jsonrpc2"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// ErrMissingBatchResponse is set on a BatchCall whose response is absent from the batch response
+var ErrMissingBatchResponse = errors.New("missing response in batch")
+
+// BatchCall is a call queued in a Batch. Error is set when the call can not be queued,
+// when the server answers it with an error or when its result can not be unmarshalled.
+type BatchCall struct {
+	Method string
+	Result interface{} // pointer the result is unmarshalled into, nil discards it
+	Error  error
+
+	req *jsonrpc2.Request
+}
+
+// Batch queues calls of the jsonrpc2 methods of a handler provided by NewClient and
+// sends them in a single HTTP request. The responses are matched to the calls by ID.
+// Over a websocket client the calls are pipelined on the connection instead.
+//
+//	type EthRpc struct {
+//		GetTransactionReceipt func(string) (Receipt, error)
+//		NewBatch              rpc.BatchFactory
+//	}
+//
+//	var eth EthRpc
+//	_ = rpc.NewClient(ctx, endpoint, "eth", &eth, nil)
+//	batch := eth.NewBatch()
+//	receipts := make([]ethereum.Receipt, len(hashes))
+//	for i, hash := range hashes {
+//		batch.Queue("GetTransactionReceipt", &receipts[i], hash)
+//	}
+//	err := batch.Send(ctx) // then check each BatchCall.Error
+//
+// The response cache of the methods is neither read nor written by batches.
+// A Batch is not safe for concurrent use.
+type Batch struct {
+	client *Client
+	calls  []*BatchCall
+}
+
+// BatchFactory is the type of a handler field set by NewClient to create batches of calls to the handler
+type BatchFactory func() *Batch
+
+var batchFactoryType = reflect.TypeOf(BatchFactory(nil))
+
+// NewBatch creates an empty batch of calls to handler, which must have been provided by NewClient
+// or NewClientWithCustomFetch and have a BatchFactory field
+func NewBatch(handler interface{}) (*Batch, error) {
+	val := reflect.ValueOf(handler)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return nil, errors.New("handler must point to a struct")
+	}
+	for i := 0; i < val.Elem().NumField(); i++ {
+		if f := val.Elem().Field(i); f.Type() == batchFactoryType && !f.IsNil() {
+			return f.Interface().(BatchFactory)(), nil
+		}
+	}
+	return nil, errors.New("handler has no BatchFactory provided by a rpc client")
+}
+
+func (c *Client) newBatch() *Batch {
+	return &Batch{client: c}
+}
+
+// Queue adds a call of the handler's function field to the batch, args are the arguments
+// of the function without its context. The returned call holds the result and error once sent.
+func (b *Batch) Queue(field string, result interface{}, args ...interface{}) *BatchCall {
+	call := &BatchCall{Method: field, Result: result}
+	b.calls = append(b.calls, call)
+
+	fn, ok := b.client.funcs[field]
+	if !ok {
+		call.Error = &ErrClient{fmt.Errorf("handler has no method %s", field)}
+		return call
+	}
+	call.Method = fn.name
+	if fn.rpcType != typeJsonrpc2 {
+		call.Error = &ErrClient{fmt.Errorf("%s is not a jsonrpc2 method", field)}
+		return call
+	}
+	if result != nil && reflect.TypeOf(result).Kind() != reflect.Ptr {
+		call.Error = &ErrClient{fmt.Errorf("result of %s must be a pointer", field)}
+		return call
+	}
+
+	values, err := fn.argValues(args)
+	if err != nil {
+		call.Error = &ErrClient{err}
+		return call
+	}
+
+	id := atomic.AddInt64(&b.client.idCtr, 1)
+	call.req, err = jsonrpc2.NewCall(jsonrpc2.Int64ID(id), fn.name, fn.jsonrpc2Params(values))
+	if err != nil {
+		call.Error = &ErrClient{fmt.Errorf("create request failed: %w", err)}
+	}
+	return call
+}
+
+// Calls returns the queued calls in queue order
+func (b *Batch) Calls() []*BatchCall {
+	return b.calls
+}
+
+// Len returns the number of queued calls
+func (b *Batch) Len() int {
+	return len(b.calls)
+}
+
+// Send posts the queued calls which have no error yet in one request. The returned error is
+// a failure of the whole batch, e.g. the transport or a server not supporting batches;
+// the errors of single calls are set on their BatchCall.
+func (b *Batch) Send(ctx context.Context) error {
+	pending := make(map[int64]*BatchCall, len(b.calls))
+	var sent []*BatchCall
+	var msgs []jsonrpc2.Message
+	for _, call := range b.calls {
+		if call.req == nil || call.Error != nil {
+			continue
+		}
+		pending[call.req.ID.Raw().(int64)] = call
+		sent = append(sent, call)
+		msgs = append(msgs, call.req)
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	responses, err := b.client.sendBatchJsonrpc2(ctx, msgs)
+	if err != nil {
+		for _, call := range sent {
+			b.client.observe(call.Method, start, err, true)
+		}
+		return err
+	}
+	defer func() {
+		for _, call := range sent {
+			b.client.observe(call.Method, start, call.Error, true)
+		}
+	}()
+
+	for _, msg := range responses {
+		resp, ok := msg.(*jsonrpc2.Response)
+		if !ok {
+			continue
+		}
+		id, ok := resp.ID.Raw().(int64)
+		if !ok {
+			continue
+		}
+		call, ok := pending[id]
+		if !ok {
+			continue
+		}
+		delete(pending, id)
+
+		if resp.Error != nil {
+			call.Error = resp.Error
+			continue
+		}
+		if call.Result != nil && resp.Result != nil {
+			if err = json.Unmarshal(resp.Result, call.Result); err != nil {
+				b.client.log.Warn("unmarshalling failed", "method", call.Method, "message", string(resp.Result))
+				call.Error = &ErrClient{fmt.Errorf("unmarshalling result: %w", err)}
+			}
+		}
+	}
+
+	for _, call := range pending {
+		call.Error = ErrMissingBatchResponse
+	}
+
+	return nil
+}
+
+func (c *Client) sendBatchJsonrpc2(ctx context.Context, msgs []jsonrpc2.Message) ([]jsonrpc2.Message, error) {
+	if c.ws != nil {
+		return c.ws.sendAll(ctx, msgs)
+	}
+
+	data, err := jsonrpc2.EncodeBatchMessage(msgs)
+	if err != nil {
+		return nil, &ErrClient{err}
+	}
+	rawResp, err := c.fetch.Post("").WithContext(ctx).SetJSONBody(data).Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	body := rawResp.BodyBytes()
+	if len(body) > 0 && body[0] != '[' {
+		// a server rejecting the whole batch answers with a single error response
+		msg, err := jsonrpc2.DecodeMessage(body)
+		if err != nil {
+			return nil, &ErrClient{err}
+		}
+		if resp, ok := msg.(*jsonrpc2.Response); ok && resp.Error != nil {
+			return nil, resp.Error
+		}
+		return nil, &ErrClient{errors.New("unexpected non batch response")}
+	}
+
+	msgs, err = jsonrpc2.DecodeBatchMessage(body)
+	if err != nil {
+		return nil, &ErrClient{err}
+	}
+	return msgs, nil
+}
+
+// argValues checks args against the function's parameters, context excluded
+func (fn *rpcFunc) argValues(args []interface{}) ([]reflect.Value, error) {
+	first := 0
+	if fn.hasCtx {
+		first = 1
+	}
+	if len(args) != fn.ftyp.NumIn()-first {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", fn.name, fn.ftyp.NumIn()-first, len(args))
+	}
+
+	values := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		in := fn.ftyp.In(first + i)
+		if arg == nil {
+			switch in.Kind() {
+			case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+				values[i] = reflect.Zero(in)
+				continue
+			}
+			return nil, fmt.Errorf("argument %d of %s can not be nil", i, fn.name)
+		}
+		v := reflect.ValueOf(arg)
+		if !v.Type().AssignableTo(in) {
+			return nil, fmt.Errorf("argument %d of %s must be %s, got %s", i, fn.name, in, v.Type())
+		}
+		values[i] = v
+	}
+	return values, nil
+}
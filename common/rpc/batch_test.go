@@ -0,0 +1,129 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchServer answers Inc with its argument plus one and fails Hello,
+// requests of the method Drop get no response
+func batchServer(t *testing.T, posts *int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*posts++
+		body, _ := io.ReadAll(r.Body)
+		var reqs []struct {
+			ID     int64             `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"batch not supported"}}`))
+			return
+		}
+
+		var resps []map[string]interface{}
+		// answer in reverse order, responses are matched by id
+		for i := len(reqs) - 1; i >= 0; i-- {
+			req := reqs[i]
+			resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+			switch req.Method {
+			case "Inc":
+				var v int
+				_ = json.Unmarshal(req.Params[0], &v)
+				resp["result"] = v + 1
+			case "Hello":
+				resp["error"] = map[string]interface{}{"code": -32000, "message": "hello failed"}
+			default:
+				continue
+			}
+			resps = append(resps, resp)
+		}
+		_ = json.NewEncoder(w).Encode(resps)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+type batchSvc struct {
+	Inc   func(int) (int, error)
+	Hello func(context.Context) (string, error)
+	Drop  func() (int, error)
+	Echo  func(string) (string, error) `rpc:"rest" method:"POST" name:"echo"`
+
+	NewBatch BatchFactory
+}
+
+func Test_Batch_Send(t *testing.T) {
+	var posts int
+	server := batchServer(t, &posts)
+	var s batchSvc
+	require.NoError(t, NewClient(context.Background(), server.URL, "test", &s, map[string]string{}))
+
+	batch, err := NewBatch(&s)
+	require.NoError(t, err)
+
+	results := make([]int, 3)
+	for i := range results {
+		batch.Queue("Inc", &results[i], i*10)
+	}
+	var hello string
+	helloCall := batch.Queue("Hello", &hello)
+	dropCall := batch.Queue("Drop", nil)
+	unknownCall := batch.Queue("Unknown", nil)
+	restCall := batch.Queue("Echo", nil, "ping")
+	badArgCall := batch.Queue("Inc", nil, "ten")
+	assert.Equal(t, 8, batch.Len())
+
+	require.NoError(t, batch.Send(context.Background()))
+	assert.Equal(t, 1, posts)
+	assert.Equal(t, []int{1, 11, 21}, results)
+	for _, call := range batch.Calls()[:3] {
+		assert.NoError(t, call.Error)
+		assert.Equal(t, "Inc", call.Method)
+	}
+
+	require.Error(t, helloCall.Error)
+	assert.Contains(t, helloCall.Error.Error(), "hello failed")
+	assert.ErrorIs(t, dropCall.Error, ErrMissingBatchResponse)
+	assert.Error(t, unknownCall.Error)
+	assert.Error(t, restCall.Error)
+	assert.Error(t, badArgCall.Error)
+}
+
+func Test_Batch_Rejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"batch not supported"}}`))
+	}))
+	defer server.Close()
+	var s batchSvc
+	require.NoError(t, NewClient(context.Background(), server.URL, "test", &s, map[string]string{}))
+
+	batch, err := NewBatch(&s)
+	require.NoError(t, err)
+	batch.Queue("Inc", nil, 1)
+	err = batch.Send(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "batch not supported")
+}
+
+func Test_NewBatch_UnknownHandler(t *testing.T) {
+	_, err := NewBatch(&batchSvc{})
+	assert.Error(t, err)
+
+	// a handler without a BatchFactory field can not batch
+	var s struct {
+		Inc func(int) (int, error)
+	}
+	require.NoError(t, NewClient(context.Background(), "http://127.0.0.1:1", "test", &s, map[string]string{}))
+	_, err = NewBatch(&s)
+	assert.Error(t, err)
+	_, err = NewBatch(s)
+	assert.Error(t, err)
+}
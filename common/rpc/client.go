@@ -1,18 +1,22 @@
 package rpc
 
 import (
+	"bytes"
 	"context"
 	"crypto-trade-client/common/cache"
 	"crypto-trade-client/common/rpc/jsonrpc2"
 	"crypto-trade-client/common/tag"
 	"crypto-trade-client/common/web"
 	"crypto-trade-client/common/web/fetch"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/hashicorp/go-hclog"
 	"go/token"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -31,7 +35,7 @@ func (e *ErrClient) Error() string {
 }
 
 // Unwrap unwraps the actual error
-func (e *ErrClient) Unwrap(err error) error {
+func (e *ErrClient) Unwrap() error {
 	return e.err
 }
 
@@ -39,10 +43,16 @@ func (e *ErrClient) Unwrap(err error) error {
 type ClientCloser func()
 
 type Client struct {
+	name         string
 	fetch        fetch.ClientInterface
 	idCtr        int64
 	cacheStorage *cache.Cache
 	log          hclog.Logger
+	metrics      MetricsHook // set from the handler's MetricsHook field, nil uses the hook set by SetMetricsHook
+
+	ws *wsTransport // set by NewWebSocketClient, jsonrpc2 calls are then sent over it instead of fetch
+
+	funcs map[string]*rpcFunc // by handler field name
 }
 
 // NewClient creates new jsonrpc 2.0 client
@@ -54,7 +64,7 @@ func NewClient(ctx context.Context, endpoint, clientName string, handler interfa
 	l := hclog.L().Named("jsonrpc2." + clientName)
 
 	fetchClient := fetch.NewClientWithEndpoint(endpoint, l).AddHeaders(headers)
-	client := &Client{log: l, fetch: fetchClient, cacheStorage: cache.NewCache()}
+	client := &Client{name: clientName, log: l, fetch: fetchClient, cacheStorage: cache.NewCache()}
 
 	return client.provide(handler)
 }
@@ -62,7 +72,7 @@ func NewClient(ctx context.Context, endpoint, clientName string, handler interfa
 func NewClientWithCustomFetch(ctx context.Context, fetchClient fetch.ClientInterface, clientName string, handler interface{}) error {
 	l := hclog.L().Named("jsonrpc2." + clientName)
 
-	client := &Client{log: l, fetch: fetchClient, cacheStorage: cache.NewCache()}
+	client := &Client{name: clientName, log: l, fetch: fetchClient, cacheStorage: cache.NewCache()}
 
 	return client.provide(handler)
 }
@@ -90,6 +100,10 @@ func (c *Client) provide(handler interface{}) error {
 			for _, m := range h.BeforeRequest() {
 				f.OnBeforeRequest(m)
 			}
+		case *fetch.BalancedClient:
+			for _, m := range h.BeforeRequest() {
+				f.OnBeforeRequest(m)
+			}
 		}
 	}
 
@@ -102,13 +116,25 @@ func (c *Client) provide(handler interface{}) error {
 		namePrefix = h.Namespace() + h.NamespaceSeparator()
 	}
 
+	c.funcs = make(map[string]*rpcFunc, etype.NumField())
 	for i := 0; i < etype.NumField(); i++ {
+		if etype.Field(i).Type == batchFactoryType {
+			val.Elem().Field(i).Set(reflect.ValueOf(BatchFactory(c.newBatch)))
+			continue
+		}
+		if etype.Field(i).Type == metricsHookType {
+			if hook := val.Elem().Field(i); !hook.IsNil() {
+				c.metrics = hook.Interface().(MetricsHook)
+			}
+			continue
+		}
 		fn, err := c.makeRpcFunc(etype.Field(i), convention, namePrefix)
 		if err != nil {
 			c.log.Warn("unsupported method type", "err", err)
 			return err
 		}
-		val.Elem().Field(i).Set(fn)
+		c.funcs[etype.Field(i).Name] = fn
+		val.Elem().Field(i).Set(reflect.MakeFunc(fn.ftyp, fn.handleRpcCall))
 	}
 
 	return nil
@@ -123,10 +149,10 @@ func isExportedOrBuiltinType(t reflect.Type) bool {
 	return token.IsExported(t.Name()) || t.PkgPath() == ""
 }
 
-func (c *Client) makeRpcFunc(field reflect.StructField, convention NamingConvention, namePrefix string) (reflect.Value, error) {
+func (c *Client) makeRpcFunc(field reflect.StructField, convention NamingConvention, namePrefix string) (*rpcFunc, error) {
 	ftyp := field.Type
 	if ftyp.Kind() != reflect.Func {
-		return reflect.Value{}, errors.New("handler field must be func")
+		return nil, errors.New("handler field must be func")
 	}
 
 	hasCtx := false
@@ -141,13 +167,13 @@ func (c *Client) makeRpcFunc(field reflect.StructField, convention NamingConvent
 	if ftyp.NumIn() > argPos {
 		argType = ftyp.In(argPos)
 		if !isExportedOrBuiltinType(argType) {
-			return reflect.Value{}, errors.New(fmt.Sprintf("%s is not exported", field.Name))
+			return nil, errors.New(fmt.Sprintf("%s is not exported", field.Name))
 		}
 	}
 
 	// Method out at most 2.
 	if ftyp.NumOut() > 2 {
-		return reflect.Value{}, errors.New(fmt.Sprintf("%s out num are greater than 2", field.Name))
+		return nil, errors.New(fmt.Sprintf("%s out num are greater than 2", field.Name))
 	}
 
 	errPos := -1
@@ -157,17 +183,28 @@ func (c *Client) makeRpcFunc(field reflect.StructField, convention NamingConvent
 	switch {
 	case ftyp.NumOut() == 1:
 		if ftyp.Out(0) != typeOfError {
-			return reflect.Value{}, errors.New("error must be returned")
+			return nil, errors.New("error must be returned")
 		}
 		errPos = 0
 	case ftyp.NumOut() == 2:
 		if ftyp.Out(1) != typeOfError {
-			return reflect.Value{}, errors.New("error must be the last return value")
+			return nil, errors.New("error must be the last return value")
 		}
 		valOutPos = 0
 		errPos = 1
 	}
 
+	// a channel returned is a subscription, it lives as long as the context
+	returnsChannel := valOutPos != -1 && ftyp.Out(valOutPos).Kind() == reflect.Chan
+	if returnsChannel {
+		if ftyp.Out(valOutPos).ChanDir()&reflect.RecvDir == 0 {
+			return nil, fmt.Errorf("%s must return a receive channel", field.Name)
+		}
+		if !hasCtx {
+			return nil, fmt.Errorf("%s returns a channel, its first argument must be a context", field.Name)
+		}
+	}
+
 	// rpcType
 	var rt rpcType
 	switch field.Tag.Get("rpc") {
@@ -193,7 +230,18 @@ func (c *Client) makeRpcFunc(field reflect.StructField, convention NamingConvent
 			name = field.Name
 		}
 	}
-	name = namePrefix + name
+	// subscriptions call `<namespace>_subscribe` with their name as first param
+	var subscription, unsubscribe string
+	if returnsChannel {
+		if rt != typeJsonrpc2 {
+			return nil, fmt.Errorf("%s returns a channel, only jsonrpc2 methods can subscribe", field.Name)
+		}
+		subscription = name
+		name = namePrefix + "subscribe"
+		unsubscribe = namePrefix + "unsubscribe"
+	} else {
+		name = namePrefix + name
+	}
 
 	// rpc parameter container type
 	containerT := arrayParamType
@@ -218,10 +266,10 @@ func (c *Client) makeRpcFunc(field reflect.StructField, convention NamingConvent
 	}
 
 	// only GET method support cache
-	if found {
+	if found && !returnsChannel && valOutPos != -1 {
 		cacheCtl.cacheable = true
 		settings := tag.ParseTagSettings(cacheTag, ",")
-		cacheCtl.key = c.cacheKeyOf(settings, httpMethod, name)
+		cacheCtl.key, cacheCtl.perParams = c.cacheKeyOf(settings, httpMethod, name)
 		ttl, foundTtl := settings["ttl"]
 		if foundTtl {
 			d, err := time.ParseDuration(ttl)
@@ -229,6 +277,17 @@ func (c *Client) makeRpcFunc(field reflect.StructField, convention NamingConvent
 				cacheCtl.ttl = d
 			}
 		}
+		_, cacheCtl.immutable = settings["immutable"]
+		if cacheCtl.immutable || cacheCtl.perParams {
+			// one entry per params, the number of entries must be bounded
+			size := defaultCacheSize
+			if v, foundSize := settings["size"]; foundSize {
+				if n, err := strconv.Atoi(v); err == nil && n > 0 {
+					size = n
+				}
+			}
+			cacheCtl.lru = cache.NewLRU(size)
+		}
 		c.log.Trace("cache control of "+name, "settings", cacheCtl)
 	}
 
@@ -245,29 +304,42 @@ func (c *Client) makeRpcFunc(field reflect.StructField, convention NamingConvent
 		rpcType:            rt,
 		hasCtx:             hasCtx,
 		cacheCtl:           cacheCtl,
+
+		returnValueIsChannel: returnsChannel,
+		subscription:         subscription,
+		unsubscribe:          unsubscribe,
 	}
 
-	return reflect.MakeFunc(ftyp, f.handleRpcCall), nil
+	return f, nil
 }
 
-func (c *Client) cacheKeyOf(settings map[string]string, httpMethod, fname string) string {
+// cacheKeyOf returns the cache key of the method and whether the params are part of it.
+// An explicit key, or `-name` for the method name, is shared by all the params.
+func (c *Client) cacheKeyOf(settings map[string]string, httpMethod, fname string) (string, bool) {
 	k, found := settings["key"]
 	if found {
 		switch k {
 		case "-name":
-			return fname
+			return fname, false
+		case "":
+		default:
+			return k, false
 		}
 	}
 	// fallback
-	return httpMethod + ":" + fname
+	return httpMethod + ":" + fname, true
 }
 
-func (c *Client) sendJsonrpc2(req *jsonrpc2.Request) (*jsonrpc2.Response, error) {
+func (c *Client) sendJsonrpc2(ctx context.Context, req *jsonrpc2.Request) (*jsonrpc2.Response, error) {
+	if c.ws != nil {
+		return c.ws.send(ctx, req)
+	}
+
 	data, err := jsonrpc2.EncodeMessage(req)
 	if err != nil {
 		return nil, err
 	}
-	rawResp, err := c.fetch.Post("").SetJSONBody(data).Execute()
+	rawResp, err := c.fetch.Post("").WithContext(ctx).SetJSONBody(data).Execute()
 	if err != nil {
 		return nil, err
 	}
@@ -278,8 +350,11 @@ func (c *Client) sendJsonrpc2(req *jsonrpc2.Request) (*jsonrpc2.Response, error)
 	return msg.(*jsonrpc2.Response), err
 }
 
-func (c *Client) sendRest(method, name string, param interface{}) (*fetch.Response, error) {
-	return c.fetch.Do(method, name).SetJSONBody(param).Execute()
+func (c *Client) sendRest(ctx context.Context, method, name string, param interface{}) (*fetch.Response, error) {
+	if c.fetch == nil {
+		return nil, errors.New("rest methods are not supported over websocket")
+	}
+	return c.fetch.Do(method, name).WithContext(ctx).SetJSONBody(param).Execute()
 }
 
 type rpcType int
@@ -289,10 +364,38 @@ const (
 	typeRest
 )
 
+// defaultCacheSize is the number of responses kept by a method cached per params
+const defaultCacheSize = 1024
+
+// cacheControl is parsed from the `cache` tag of a handler field, the settings are separated by commas:
+//
+//	ttl:5s          keep the responses for 5 seconds, default is 1s
+//	immutable       keep the responses without expiration in an LRU, for data which never changes,
+//	                e.g. a block by hash. Empty and null results are never cached.
+//	size:4096       number of responses kept per params, default is defaultCacheSize
+//	key:-name       share one entry for all the params, keyed by the method name or the given key
+//
+// Without a key, the responses are cached per params, by a hash of the marshalled params, in an LRU of the method
+// bounded by size. The entries shared by all the params are kept with their ttl in the client's cache.
 type cacheControl struct {
 	cacheable bool
 	key       string
+	perParams bool
 	ttl       time.Duration
+	immutable bool
+	lru       *cache.LRU // nil caches in the client's cache
+}
+
+// keyOf returns the cache key of the marshalled params, empty when the method is not cacheable
+func (cc *cacheControl) keyOf(params []byte) string {
+	if !cc.cacheable {
+		return ""
+	}
+	if !cc.perParams {
+		return cc.key
+	}
+	sum := sha256.Sum256(params)
+	return cc.key + ":" + hex.EncodeToString(sum[:16])
 }
 
 type paramContainerType string
@@ -316,6 +419,8 @@ type rpcFunc struct {
 
 	hasCtx               bool
 	returnValueIsChannel bool
+	subscription         string // name of the subscription when returnValueIsChannel
+	unsubscribe          string // method cancelling the subscription
 
 	rpcType    rpcType
 	httpMethod string // http rpc method, it is ignored when rpcType == typeJsonrpc2
@@ -325,20 +430,46 @@ type rpcFunc struct {
 	cacheCtl cacheControl
 }
 
-func (fn *rpcFunc) responseFromCache() ([]reflect.Value, bool) {
-	if fn.cacheCtl.cacheable && fn.cacheCtl.key != "" {
-		if v, ok := fn.client.cacheStorage.Get(fn.cacheCtl.key); ok {
-			out := make([]reflect.Value, fn.nout)
-			if fn.errOut != -1 {
-				out[fn.errOut] = reflect.New(typeOfError).Elem()
-			}
-			if fn.valOut != -1 {
-				out[fn.valOut] = v.(reflect.Value)
-			}
-			return out, true
-		}
+// responseFromCache unmarshals the cached raw result of the key
+func (fn *rpcFunc) responseFromCache(key string) ([]reflect.Value, bool) {
+	if key == "" {
+		return nil, false
+	}
+
+	var v interface{}
+	var ok bool
+	if fn.cacheCtl.lru != nil {
+		v, ok = fn.cacheCtl.lru.Get(key)
+	} else {
+		v, ok = fn.client.cacheStorage.Get(key)
+	}
+	if !ok {
+		return nil, false
+	}
+
+	// every hit unmarshals its own value, the callers can not alter the cached response
+	val := reflect.New(fn.ftyp.Out(fn.valOut))
+	if err := json.Unmarshal(v.([]byte), val.Interface()); err != nil {
+		return nil, false
+	}
+	return fn.processResponse(nil, val.Elem()), true
+}
+
+// saveToCache caches the raw result of a successful call
+func (fn *rpcFunc) saveToCache(key string, raw []byte) {
+	if key == "" || len(raw) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		// a missing result, e.g. a receipt not mined yet, may exist later
+		return
+	}
+	raw = append([]byte(nil), raw...)
+	switch {
+	case fn.cacheCtl.immutable:
+		fn.cacheCtl.lru.Save(key, raw)
+	case fn.cacheCtl.lru != nil:
+		fn.cacheCtl.lru.SaveWithTTL(key, raw, fn.cacheCtl.ttl)
+	default:
+		fn.client.cacheStorage.Save(key, raw, fn.cacheCtl.ttl)
 	}
-	return nil, false
 }
 
 func (fn *rpcFunc) processResponse(err error, rval reflect.Value) []reflect.Value {
@@ -346,10 +477,6 @@ func (fn *rpcFunc) processResponse(err error, rval reflect.Value) []reflect.Valu
 
 	if fn.valOut != -1 {
 		out[fn.valOut] = rval
-		if fn.cacheCtl.cacheable && err == nil {
-			// cache the result when cache is enabled and err is nil
-			fn.client.cacheStorage.Save(fn.cacheCtl.key, rval, fn.cacheCtl.ttl)
-		}
 	}
 	if fn.errOut != -1 {
 		out[fn.errOut] = reflect.New(typeOfError).Elem()
@@ -375,6 +502,21 @@ func (fn *rpcFunc) processError(err error) []reflect.Value {
 	return out
 }
 
+// jsonrpc2Params builds the params of the request from the call arguments, context excluded
+func (fn *rpcFunc) jsonrpc2Params(args []reflect.Value) interface{} {
+	if fn.paramContainerType == objectParamType {
+		if len(args) > 0 {
+			return args[0].Interface()
+		}
+		return nil
+	}
+	arrayParam := make([]interface{}, len(args))
+	for i, arg := range args {
+		arrayParam[i] = arg.Interface()
+	}
+	return arrayParam
+}
+
 func (fn *rpcFunc) handleRpcCall(args []reflect.Value) (results []reflect.Value) {
 	id := atomic.AddInt64(&fn.client.idCtr, 1)
 	apos := 0
@@ -394,31 +536,29 @@ func (fn *rpcFunc) handleRpcCall(args []reflect.Value) (results []reflect.Value)
 	default:
 	}
 
-	// try to get the response from cache
-	if r, ok := fn.responseFromCache(); ok {
-		return r
+	if fn.returnValueIsChannel {
+		return fn.handleSubscribe(ctx, args[apos:])
 	}
 
 	// handle jsonrpc2
 	if fn.rpcType == typeJsonrpc2 {
-		var param interface{}
-		if fn.paramContainerType == objectParamType {
-			if apos < len(args) {
-				param = args[apos].Interface()
-			}
-		} else {
-			arrayParam := make([]interface{}, len(args)-apos)
-			for i, arg := range args[apos:] {
-				arrayParam[i] = arg.Interface()
-			}
-			param = arrayParam
+		req, err := jsonrpc2.NewCall(jsonrpc2.Int64ID(id), fn.name, fn.jsonrpc2Params(args[apos:]))
+		if err != nil {
+			return fn.processError(fmt.Errorf("create request failed: %w", err))
 		}
 
-		req, err := jsonrpc2.NewCall(jsonrpc2.Int64ID(id), fn.name, param)
+		// try to get the response from cache
+		cacheKey := fn.cacheCtl.keyOf(req.Params)
+		if r, ok := fn.responseFromCache(cacheKey); ok {
+			return r
+		}
+		start := time.Now()
+		resp, err := fn.client.sendJsonrpc2(ctx, req)
 		if err != nil {
-			return fn.processError(fmt.Errorf("create request failed: %w", err))
+			fn.client.observe(fn.name, start, err, false)
+		} else {
+			fn.client.observe(fn.name, start, resp.Error, false)
 		}
-		resp, err := fn.client.sendJsonrpc2(req)
 		if err != nil {
 			switch err.(type) {
 			case web.ServerError:
@@ -445,6 +585,9 @@ func (fn *rpcFunc) handleRpcCall(args []reflect.Value) (results []reflect.Value)
 			retVal = func() reflect.Value { return val.Elem() }
 		}
 
+		if resp.Error == nil {
+			fn.saveToCache(cacheKey, resp.Result)
+		}
 		return fn.processResponse(resp.Error, retVal())
 	} else {
 		// handle rest
@@ -453,7 +596,22 @@ func (fn *rpcFunc) handleRpcCall(args []reflect.Value) (results []reflect.Value)
 			p = args[apos].Interface()
 		}
 
-		resp, err := fn.client.sendRest(fn.httpMethod, fn.name, p)
+		// try to get the response from cache
+		var cacheKey string
+		if fn.cacheCtl.cacheable {
+			params, err := json.Marshal(p)
+			if err != nil {
+				return fn.processError(fmt.Errorf("marshalling params: %w", err))
+			}
+			cacheKey = fn.cacheCtl.keyOf(params)
+		}
+		if r, ok := fn.responseFromCache(cacheKey); ok {
+			return r
+		}
+
+		start := time.Now()
+		resp, err := fn.client.sendRest(ctx, fn.httpMethod, fn.name, p)
+		fn.client.observe(fn.name, start, err, false)
 		if err != nil {
 			switch err.(type) {
 			case web.ServerError:
@@ -474,6 +632,7 @@ func (fn *rpcFunc) handleRpcCall(args []reflect.Value) (results []reflect.Value)
 
 			retVal = func() reflect.Value { return val.Elem() }
 		}
+		fn.saveToCache(cacheKey, resp.BodyBytes())
 		return fn.processResponse(nil, retVal())
 	}
 }
@@ -309,3 +309,105 @@ func (h *handler) Handle(ctx context.Context, req *jsonrpc2.Request) (interface{
 		return nil, jsonrpc2.ErrNotHandled
 	}
 }
+
+type cacheSvc struct {
+	Block   func(int) (map[string]int, error) `cache:"immutable,size:2"`
+	Price   func() (int, error)               `cache:"ttl:200ms"`
+	Receipt func(string) (*int, error)        `cache:"immutable"`
+	Balance func(string) (int, error)         `cache:"ttl:200ms,size:2"`
+}
+
+func Test_client_jsonrpc2_cache_params(t *testing.T) {
+	calls := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int64             `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		calls[req.Method]++
+		var result interface{}
+		switch req.Method {
+		case "Block":
+			var n int
+			_ = json.Unmarshal(req.Params[0], &n)
+			result = map[string]int{"number": n}
+		case "Price":
+			result = calls[req.Method]
+		case "Receipt":
+			// not mined yet
+			result = nil
+		case "Balance":
+			result = calls[req.Method]
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	defer server.Close()
+
+	var s cacheSvc
+	require.NoError(t, NewClient(context.Background(), server.URL, "test", &s, map[string]string{}))
+
+	// each params has its own entry
+	for i := 0; i < 3; i++ {
+		for _, n := range []int{100, 101} {
+			block, err := s.Block(n)
+			require.NoError(t, err)
+			assert.Equal(t, n, block["number"])
+		}
+	}
+	assert.Equal(t, 2, calls["Block"])
+
+	// a hit can not alter the cached response
+	block, err := s.Block(100)
+	require.NoError(t, err)
+	block["number"] = 0
+	block, err = s.Block(100)
+	require.NoError(t, err)
+	assert.Equal(t, 100, block["number"])
+
+	// the least recently used block is evicted
+	_, err = s.Block(102)
+	require.NoError(t, err)
+	_, err = s.Block(101)
+	require.NoError(t, err)
+	assert.Equal(t, 4, calls["Block"])
+
+	// ttl
+	for i := 0; i < 3; i++ {
+		price, err := s.Price()
+		require.NoError(t, err)
+		assert.Equal(t, 1, price)
+	}
+	time.Sleep(300 * time.Millisecond)
+	price, err := s.Price()
+	require.NoError(t, err)
+	assert.Equal(t, 2, price)
+
+	// null results are not cached
+	for i := 0; i < 2; i++ {
+		receipt, err := s.Receipt("0x01")
+		require.NoError(t, err)
+		assert.Nil(t, receipt)
+	}
+	assert.Equal(t, 2, calls["Receipt"])
+
+	// the ttl entries per params are bounded by the size
+	for _, account := range []string{"a", "b", "a", "b"} {
+		_, err = s.Balance(account)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, calls["Balance"])
+	_, err = s.Balance("c")
+	require.NoError(t, err)
+	balance, err := s.Balance("b")
+	require.NoError(t, err)
+	assert.Equal(t, 2, balance)
+	balance, err = s.Balance("a")
+	require.NoError(t, err)
+	assert.Equal(t, 4, balance)
+	time.Sleep(300 * time.Millisecond)
+	balance, err = s.Balance("a")
+	require.NoError(t, err)
+	assert.Equal(t, 5, balance)
+}
@@ -0,0 +1,63 @@
+package rpc
+
+import (
+	"context"
+	"crypto-trade-client/common/web"
+	"encoding/json"
+	"github.com/hashicorp/go-hclog"
+	"time"
+)
+
+// CallInfo describes the service call passed to the interceptors
+type CallInfo struct {
+	Method  string          // method as requested, e.g. wallet_transfer
+	Service string          // registered service name
+	Name    string          // Go method name
+	Params  json.RawMessage // raw params, unmarshalled by the innermost invoker
+	IsCall  bool            // false for a notification, whose result is dropped
+}
+
+// Invoker continues the call, with the next interceptor or the method itself
+type Invoker func(ctx context.Context) (interface{}, error)
+
+// Interceptor wraps the service calls, e.g. for logging, authentication or timing.
+// It calls next to continue the call, possibly with a derived context,
+// or returns without calling it to reject the call.
+//
+//	registry.Use(func(ctx context.Context, call *rpc.CallInfo, next rpc.Invoker) (interface{}, error) {
+//		if req, ok := rpc.HTTPRequestFromContext(ctx); !ok || req.Header.Get("X-Chain-Appid") == "" {
+//			return nil, jsonrpc2.NewError(-32050, "unauthorized")
+//		}
+//		return next(ctx)
+//	})
+type Interceptor func(ctx context.Context, call *CallInfo, next Invoker) (interface{}, error)
+
+// chain wraps invoke with the interceptors, the first one is the outermost
+func chain(global, method []Interceptor, info *CallInfo, invoke Invoker) Invoker {
+	interceptors := make([]Interceptor, 0, len(global)+len(method))
+	interceptors = append(interceptors, global...)
+	interceptors = append(interceptors, method...)
+
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoke
+		invoke = func(ctx context.Context) (interface{}, error) {
+			return interceptor(ctx, info, next)
+		}
+	}
+	return invoke
+}
+
+// LogCalls logs every call with its duration and trace id, at debug level when it succeeds and warn level when it fails
+func LogCalls(logger hclog.Logger) Interceptor {
+	return func(ctx context.Context, call *CallInfo, next Invoker) (interface{}, error) {
+		start := time.Now()
+		result, err := next(ctx)
+		logger := web.TraceLogger(ctx, logger)
+		if err != nil {
+			logger.Warn("rpc call failed", "method", call.Method, "elapsed", time.Since(start), "err", err)
+		} else {
+			logger.Debug("rpc call", "method", call.Method, "elapsed", time.Since(start))
+		}
+		return result, err
+	}
+}
@@ -0,0 +1,118 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"crypto-trade-client/common/rpc/jsonrpc2"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postRpc(t *testing.T, url string, appId string, body string) string {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if appId != "" {
+		req.Header.Set("X-Chain-Appid", appId)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func Test_ServiceRegistry_interceptors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	registry := (&ServiceRegistry{}).WithLogger(hclog.New(&hclog.LoggerOptions{Output: &logs}))
+	require.NoError(t, registry.Register(&Wallet{}))
+
+	var calls []string
+	trace := func(name string) Interceptor {
+		return func(ctx context.Context, call *CallInfo, next Invoker) (interface{}, error) {
+			calls = append(calls, name+">"+call.Method)
+			result, err := next(ctx)
+			calls = append(calls, name+"<"+call.Name)
+			return result, err
+		}
+	}
+	auth := func(ctx context.Context, call *CallInfo, next Invoker) (interface{}, error) {
+		if req, ok := HTTPRequestFromContext(ctx); !ok || req.Header.Get("X-Chain-Appid") != "app" {
+			return nil, jsonrpc2.NewError(-32050, "unauthorized")
+		}
+		return next(ctx)
+	}
+	registry.Use(trace("outer"), trace("inner"))
+	require.NoError(t, registry.UseFor("wallet_transfer", auth, trace("method")))
+	assert.Error(t, registry.UseFor("wallet_steal", auth))
+
+	router := gin.New()
+	router.POST("/rpc", NewServer(registry).HandleHTTP())
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+	url := ts.URL + "/rpc"
+
+	t.Run("order", func(t *testing.T) {
+		calls = nil
+		resp := postRpc(t, url, "app", `{"jsonrpc":"2.0","id":1,"method":"wallet_transfer","params":{"to":"bob","amount":1}}`)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":"bob:x"}`, resp)
+		assert.Equal(t, []string{
+			"outer>wallet_transfer", "inner>wallet_transfer", "method>wallet_transfer",
+			"method<Transfer", "inner<Transfer", "outer<Transfer",
+		}, calls)
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		calls = nil
+		resp := postRpc(t, url, "", `{"jsonrpc":"2.0","id":2,"method":"wallet_transfer","params":{"to":"bob","amount":1}}`)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":2,"error":{"code":-32050,"message":"unauthorized"}}`, resp)
+		assert.Equal(t, []string{"outer>wallet_transfer", "inner>wallet_transfer", "inner<Transfer", "outer<Transfer"}, calls)
+	})
+
+	t.Run("other methods are not authenticated", func(t *testing.T) {
+		resp := postRpc(t, url, "", `{"jsonrpc":"2.0","id":3,"method":"wallet_balance","params":["abc"]}`)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":3,"result":3}`, resp)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		calls = nil
+		resp := postRpc(t, url, "", `{"jsonrpc":"2.0","id":4,"method":"wallet_panic"}`)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":4,"error":{"code":-32603,"message":"JSON RPC internal error: method panicked"}}`, resp)
+		assert.Equal(t, []string{"outer>wallet_panic", "inner>wallet_panic"}, calls)
+		assert.Contains(t, logs.String(), "rpc method panicked")
+		assert.Contains(t, logs.String(), "method=wallet_panic")
+		assert.Contains(t, logs.String(), "panic=oops")
+		assert.Contains(t, logs.String(), "Wallet).Panic")
+	})
+}
+
+func Test_LogCalls(t *testing.T) {
+	var logs bytes.Buffer
+	registry := &ServiceRegistry{}
+	require.NoError(t, registry.Register(&Wallet{}))
+	registry.Use(LogCalls(hclog.New(&hclog.LoggerOptions{Output: &logs, Level: hclog.Debug})))
+	handler := NewJsonrpc2Handler(registry)
+
+	call, err := jsonrpc2.NewCall(jsonrpc2.Int64ID(1), "wallet_balance", []string{"ab"})
+	require.NoError(t, err)
+	result, err := handler.Handle(context.Background(), call)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result)
+	assert.Contains(t, logs.String(), "[DEBUG] rpc call: method=wallet_balance elapsed=")
+
+	call, err = jsonrpc2.NewCall(jsonrpc2.Int64ID(2), "wallet_plain", nil)
+	require.NoError(t, err)
+	_, err = handler.Handle(context.Background(), call)
+	assert.EqualError(t, err, "boom")
+	assert.Contains(t, logs.String(), "[WARN]  rpc call failed: method=wallet_plain elapsed=")
+}
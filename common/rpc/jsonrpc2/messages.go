@@ -93,6 +93,10 @@ func NewResponse(id ID, result interface{}, rerr error) (*Response, error) {
 
 func (msg *Response) marshal(to *wireCombined) {
 	to.ID = msg.ID.value
+	if to.ID == nil {
+		// a response to a request whose id could not be read has a null id
+		to.ID = json.RawMessage("null")
+	}
 	to.Error = toWireError(msg.Error)
 	to.Result = msg.Result
 }
@@ -106,7 +110,8 @@ func toWireError(err error) *wireError {
 		// already a wire error, just use it
 		return err
 	}
-	result := &wireError{Message: err.Error()}
+	// errors without a code are reported as ErrUnknown
+	result := &wireError{Code: ErrUnknown.(*wireError).Code, Message: err.Error()}
 	var wrapped *wireError
 	if errors.As(err, &wrapped) {
 		// if we wrapped a wire error, keep the code from the wrapped error
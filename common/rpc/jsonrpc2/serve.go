@@ -54,6 +54,12 @@ func Dial(ctx context.Context, dialer Dialer, binder Binder) (*Connection, error
 	return newConnection(ctx, rwc, binder)
 }
 
+// Bind builds a connection on top of an established stream using the binder,
+// e.g. a WebSocket accepted by an HTTP server.
+func Bind(ctx context.Context, rwc io.ReadWriteCloser, binder Binder) (*Connection, error) {
+	return newConnection(ctx, rwc, binder)
+}
+
 // Serve starts a new server listening for incoming connections and returns
 // it.
 // This returns a fully running and connected server, it does not block on
@@ -0,0 +1,132 @@
+package jsonrpc2
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// This file contains the WebSocket transport. WebSocket frames the messages itself, so the
+// streams are used with RawFramer: every message written is sent as one text message.
+
+const (
+	// wsPongWait is how long the stream waits for any frame before considering the peer gone
+	wsPongWait = 60 * time.Second
+	// wsPingPeriod must be shorter than wsPongWait
+	wsPingPeriod = wsPongWait * 9 / 10
+	wsCloseWait  = time.Second
+)
+
+// WebSocketDialer returns a Dialer connecting to the ws:// or wss:// url with the request headers.
+func WebSocketDialer(url string, header http.Header) Dialer {
+	return &wsDialer{url: url, header: header}
+}
+
+type wsDialer struct {
+	url    string
+	header http.Header
+}
+
+func (d *wsDialer) Dial(ctx context.Context) (io.ReadWriteCloser, error) {
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, d.url, d.header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("websocket handshake failed with status %d: %w", resp.StatusCode, err)
+		}
+		return nil, err
+	}
+	return NewWebSocketStream(conn), nil
+}
+
+// NewWebSocketStream adapts a WebSocket connection to the byte stream used by a Connection.
+// It pings the peer periodically and fails the reads when the peer stops answering.
+func NewWebSocketStream(conn *websocket.Conn) io.ReadWriteCloser {
+	s := &wsStream{conn: conn, done: make(chan struct{})}
+	_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	go s.ping()
+	return s
+}
+
+type wsStream struct {
+	conn      *websocket.Conn
+	reader    io.Reader // reader of the message being read
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *wsStream) Read(p []byte) (int, error) {
+	for {
+		if s.reader == nil {
+			_, r, err := s.conn.NextReader()
+			if err != nil {
+				return 0, s.readError(err)
+			}
+			s.reader = r
+		}
+		n, err := s.reader.Read(p)
+		if err == io.EOF {
+			// end of this message, continue with the next one
+			s.reader = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// readError reports a normal closure by either side as io.EOF
+func (s *wsStream) readError(err error) error {
+	select {
+	case <-s.done:
+		return io.EOF
+	default:
+	}
+	if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		return io.EOF
+	}
+	return err
+}
+
+func (s *wsStream) Write(p []byte) (int, error) {
+	if err := s.conn.WriteMessage(websocket.TextMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *wsStream) Close() error {
+	err := io.ErrClosedPipe
+	s.closeOnce.Do(func() {
+		close(s.done)
+		msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		_ = s.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsCloseWait))
+		err = s.conn.Close()
+	})
+	return err
+}
+
+func (s *wsStream) ping() {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsCloseWait)); err != nil {
+				// the read side fails on the missing pong
+				continue
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
@@ -6,6 +6,7 @@ package jsonrpc2
 
 import (
 	"encoding/json"
+	"errors"
 )
 
 // This file contains the go forms of the wire specification.
@@ -72,3 +73,12 @@ func NewError(code int64, message string) error {
 func (err *wireError) Error() string {
 	return err.Message
 }
+
+// ErrorCode returns the code of the JSON-RPC error wrapped in err, if any
+func ErrorCode(err error) (int64, bool) {
+	var wire *wireError
+	if errors.As(err, &wire) {
+		return wire.Code, true
+	}
+	return 0, false
+}
@@ -0,0 +1,76 @@
+package rpc
+
+import (
+	"crypto-trade-client/common/rpc/jsonrpc2"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// CallMetrics describes a call sent by a client
+type CallMetrics struct {
+	Client   string // name of the client given to NewClient
+	Method   string // JSON-RPC method, or path of REST calls
+	Code     int64  // JSON-RPC error code, 0 when the call succeeded or failed without a JSON-RPC error
+	Err      error
+	Duration time.Duration // of the whole batch for calls sent in a batch
+	Batch    bool
+}
+
+// MetricsHook observes the calls of the clients, e.g. metrics.Prometheus.
+// Calls answered from the cache are not observed.
+// It is called synchronously by every call, it must be fast and safe for concurrent use.
+//
+// A handler with a MetricsHook field set when it is given to NewClient has its calls observed by that hook
+// instead of the one set by SetMetricsHook, e.g. to report the clients of two providers to separate registries:
+//
+//	type EthRpc struct {
+//		BlockNumber func() (hexutil.Uint64, error)
+//		Metrics     rpc.MetricsHook
+//	}
+//
+//	eth := EthRpc{Metrics: providerHook}
+//	_ = rpc.NewClient(ctx, endpoint, "eth", &eth, nil)
+type MetricsHook interface {
+	ObserveCall(m CallMetrics)
+}
+
+var metricsHookType = reflect.TypeOf((*MetricsHook)(nil)).Elem()
+
+var (
+	metricsHookLock    sync.RWMutex
+	defaultMetricsHook MetricsHook
+)
+
+// SetMetricsHook sets the hook of the clients without their own, nil stops observing them
+func SetMetricsHook(hook MetricsHook) {
+	metricsHookLock.Lock()
+	defer metricsHookLock.Unlock()
+	defaultMetricsHook = hook
+}
+
+func (c *Client) metricsHook() MetricsHook {
+	if c.metrics != nil {
+		return c.metrics
+	}
+	metricsHookLock.RLock()
+	defer metricsHookLock.RUnlock()
+	return defaultMetricsHook
+}
+
+func (c *Client) observe(method string, start time.Time, err error, batch bool) {
+	hook := c.metricsHook()
+	if hook == nil {
+		return
+	}
+
+	code, _ := jsonrpc2.ErrorCode(err)
+	hook.ObserveCall(CallMetrics{
+		Client:   c.name,
+		Method:   method,
+		Code:     code,
+		Err:      err,
+		Duration: time.Since(start),
+		Batch:    batch,
+	})
+}
@@ -0,0 +1,113 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingHook struct {
+	lock  sync.Mutex
+	calls []CallMetrics
+}
+
+func (h *recordingHook) ObserveCall(m CallMetrics) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.calls = append(h.calls, m)
+}
+
+func Test_MetricsHook_Calls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not found"}}`))
+	}))
+	defer server.Close()
+
+	hook := &recordingHook{}
+	SetMetricsHook(hook)
+	defer SetMetricsHook(nil)
+
+	var s batchSvc
+	require.NoError(t, NewClient(context.Background(), server.URL, "metrics", &s, map[string]string{}))
+	_, err := s.Inc(1)
+	require.Error(t, err)
+
+	require.Len(t, hook.calls, 1)
+	call := hook.calls[0]
+	assert.Equal(t, "metrics", call.Client)
+	assert.Equal(t, "Inc", call.Method)
+	assert.Equal(t, int64(-32601), call.Code)
+	assert.Error(t, call.Err)
+	assert.False(t, call.Batch)
+}
+
+func Test_MetricsHook_Batch(t *testing.T) {
+	var posts int
+	server := batchServer(t, &posts)
+
+	hook := &recordingHook{}
+	SetMetricsHook(hook)
+	defer SetMetricsHook(nil)
+
+	var s batchSvc
+	require.NoError(t, NewClient(context.Background(), server.URL, "metrics", &s, map[string]string{}))
+	batch, err := NewBatch(&s)
+	require.NoError(t, err)
+	batch.Queue("Inc", nil, 1)
+	batch.Queue("Hello", nil)
+	require.NoError(t, batch.Send(context.Background()))
+
+	require.Len(t, hook.calls, 2)
+	sort.Slice(hook.calls, func(i, j int) bool { return hook.calls[i].Method < hook.calls[j].Method })
+	assert.Equal(t, "Hello", hook.calls[0].Method)
+	assert.Equal(t, int64(-32000), hook.calls[0].Code)
+	assert.True(t, hook.calls[0].Batch)
+	assert.Equal(t, "Inc", hook.calls[1].Method)
+	assert.NoError(t, hook.calls[1].Err)
+	assert.Zero(t, hook.calls[1].Code)
+}
+
+// hookedSvc observes its calls with its own hook
+type hookedSvc struct {
+	Inc func(int) (int, error)
+
+	Metrics MetricsHook
+}
+
+func Test_MetricsHook_PerClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not found"}}`))
+	}))
+	defer server.Close()
+
+	global := &recordingHook{}
+	SetMetricsHook(global)
+	defer SetMetricsHook(nil)
+
+	first, second := &recordingHook{}, &recordingHook{}
+	a := hookedSvc{Metrics: first}
+	require.NoError(t, NewClient(context.Background(), server.URL, "provider-a", &a, map[string]string{}))
+	b := hookedSvc{Metrics: second}
+	require.NoError(t, NewClient(context.Background(), server.URL, "provider-b", &b, map[string]string{}))
+	var c hookedSvc
+	require.NoError(t, NewClient(context.Background(), server.URL, "provider-c", &c, map[string]string{}))
+
+	for _, svc := range []hookedSvc{a, b, c} {
+		_, err := svc.Inc(1)
+		require.Error(t, err)
+	}
+
+	// each client reports to its own hook, the one without falls back to the global hook
+	require.Len(t, first.calls, 1)
+	assert.Equal(t, "provider-a", first.calls[0].Client)
+	require.Len(t, second.calls, 1)
+	assert.Equal(t, "provider-b", second.calls[0].Client)
+	require.Len(t, global.calls, 1)
+	assert.Equal(t, "provider-c", global.calls[0].Client)
+}
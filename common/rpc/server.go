@@ -1,7 +1,212 @@
 package rpc
 
-import "crypto-trade-client/common/rpc/jsonrpc2"
+import (
+	"bytes"
+	"context"
+	"crypto-trade-client/common/rpc/jsonrpc2"
+	"crypto-trade-client/common/web"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/hashicorp/go-hclog"
+	"io"
+	"net/http"
+)
 
-func NewServer() *jsonrpc2.Server {
-	return nil
+// DefaultMaxBodySize is the largest HTTP request body accepted by the Server
+const DefaultMaxBodySize = 5 * 1024 * 1024
+
+type httpRequestKey struct{}
+
+// HTTPRequestFromContext returns the HTTP request of a call served by the Server, the upgrade request
+// for calls over WebSocket. Interceptors use it to authenticate calls from the headers.
+func HTTPRequestFromContext(ctx context.Context) (*http.Request, bool) {
+	req, ok := ctx.Value(httpRequestKey{}).(*http.Request)
+	return req, ok
+}
+
+// withHTTPRequest returns ctx carrying the request, and the trace id of the request unless ctx has one,
+// e.g. set by middleware.HcLogger
+func withHTTPRequest(ctx context.Context, req *http.Request) context.Context {
+	if web.TraceIDFromContext(ctx) == "" {
+		traceID := web.TraceIDFromHeader(req.Header)
+		if traceID == "" {
+			traceID = web.NewTraceID()
+		}
+		ctx = web.WithTraceID(ctx, traceID)
+	}
+	return context.WithValue(ctx, httpRequestKey{}, req)
+}
+
+// Server serves the services of a ServiceRegistry as JSON-RPC 2.0, mounted on gin:
+//
+//	registry := &rpc.ServiceRegistry{}
+//	_ = registry.RegisterName("wallet", walletService)
+//	server := rpc.NewServer(registry)
+//	router.POST("/rpc", server.HandleHTTP())
+//	router.GET("/rpc/ws", server.HandleWebSocket())
+//
+// Over HTTP a request is a single call or a batch of calls, notifications get no response.
+// Over WebSocket every message is a single call or notification, batches are not supported.
+type Server struct {
+	handler     jsonrpc2.Handler
+	log         hclog.Logger
+	maxBodySize int64
+	upgrader    websocket.Upgrader
+}
+
+// NewServer creates a server of the registry's services
+func NewServer(registry *ServiceRegistry) *Server {
+	return &Server{
+		handler:     NewJsonrpc2Handler(registry),
+		log:         hclog.L().Named("jsonrpc2.server"),
+		maxBodySize: DefaultMaxBodySize,
+	}
+}
+
+// WithLogger sets the logger of the server
+func (s *Server) WithLogger(logger hclog.Logger) *Server {
+	s.log = logger
+	return s
+}
+
+// WithMaxBodySize sets the largest HTTP request body accepted, default is DefaultMaxBodySize
+func (s *Server) WithMaxBodySize(size int64) *Server {
+	s.maxBodySize = size
+	return s
+}
+
+// WithCheckOrigin sets the check of the Origin header of WebSocket requests,
+// by default cross-origin requests are rejected
+func (s *Server) WithCheckOrigin(check func(r *http.Request) bool) *Server {
+	s.upgrader.CheckOrigin = check
+	return s
+}
+
+// HandleHTTP serves the JSON-RPC requests posted to the route
+func (s *Server) HandleHTTP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, s.maxBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.Status(http.StatusRequestEntityTooLarge)
+				return
+			}
+			c.Status(http.StatusBadRequest)
+			return
+		}
+
+		data, err := s.serveBody(withHTTPRequest(c.Request.Context(), c.Request), body)
+		if err != nil {
+			s.log.Error("encoding response failed", "err", err)
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		if data == nil {
+			// only notifications
+			c.Status(http.StatusNoContent)
+			return
+		}
+		c.Data(http.StatusOK, web.ApplicationJSON, data)
+	}
+}
+
+// serveBody returns the encoded response of the body, nil when there is nothing to respond
+func (s *Server) serveBody(ctx context.Context, body []byte) ([]byte, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		resp := s.serveMessage(ctx, body)
+		if resp == nil {
+			return nil, nil
+		}
+		return jsonrpc2.EncodeMessage(resp)
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return jsonrpc2.EncodeMessage(errorResponse(jsonrpc2.ID{}, fmt.Errorf("%w: %v", jsonrpc2.ErrParse, err)))
+	}
+	if len(batch) == 0 {
+		return jsonrpc2.EncodeMessage(errorResponse(jsonrpc2.ID{}, fmt.Errorf("%w: empty batch", jsonrpc2.ErrInvalidRequest)))
+	}
+
+	var responses []jsonrpc2.Message
+	for _, raw := range batch {
+		if resp := s.serveMessage(ctx, raw); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		return nil, nil
+	}
+	return jsonrpc2.EncodeBatchMessage(responses)
+}
+
+// serveMessage handles a single request, it returns nil for a notification
+func (s *Server) serveMessage(ctx context.Context, raw []byte) *jsonrpc2.Response {
+	if !json.Valid(raw) {
+		return errorResponse(jsonrpc2.ID{}, jsonrpc2.ErrParse)
+	}
+	msg, err := jsonrpc2.DecodeMessage(raw)
+	if err != nil {
+		return errorResponse(jsonrpc2.ID{}, fmt.Errorf("%w: %v", jsonrpc2.ErrInvalidRequest, err))
+	}
+	req, ok := msg.(*jsonrpc2.Request)
+	if !ok {
+		return errorResponse(jsonrpc2.ID{}, jsonrpc2.ErrInvalidRequest)
+	}
+
+	result, err := s.handler.Handle(ctx, req)
+	if errors.Is(err, jsonrpc2.ErrNotHandled) {
+		err = fmt.Errorf("%w: %q", jsonrpc2.ErrMethodNotFound, req.Method)
+	}
+	if !req.IsCall() {
+		if err != nil {
+			s.log.Debug("notification failed", "method", req.Method, "err", err)
+		}
+		return nil
+	}
+
+	resp, merr := jsonrpc2.NewResponse(req.ID, result, err)
+	if merr != nil {
+		s.log.Error("marshaling result failed", "method", req.Method, "err", merr)
+		return errorResponse(req.ID, fmt.Errorf("%w: marshaling result", jsonrpc2.ErrInternal))
+	}
+	return resp
+}
+
+func errorResponse(id jsonrpc2.ID, err error) *jsonrpc2.Response {
+	return &jsonrpc2.Response{ID: id, Error: err}
+}
+
+// HandleWebSocket upgrades the requests of the route to WebSocket and serves the JSON-RPC messages
+// until the peer closes the connection
+func (s *Server) HandleWebSocket() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ws, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// the upgrader has already responded
+			s.log.Debug("websocket upgrade failed", "err", err)
+			return
+		}
+
+		// the calls of the connection see the upgrade request and the values of its context, e.g. the trace id,
+		// but outlive its cancellation which comes with the end of the handler
+		ctx := context.WithoutCancel(c.Request.Context())
+		conn, err := jsonrpc2.Bind(withHTTPRequest(ctx, c.Request), jsonrpc2.NewWebSocketStream(ws), jsonrpc2.ConnectionOptions{
+			Framer:  jsonrpc2.RawFramer(),
+			Handler: s.handler,
+		})
+		if err != nil {
+			_ = ws.Close()
+			return
+		}
+		if err = conn.Wait(); err != nil && !errors.Is(err, io.EOF) {
+			s.log.Debug("websocket connection closed", "err", err)
+		}
+		_ = conn.Close()
+	}
 }
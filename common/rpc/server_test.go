@@ -0,0 +1,281 @@
+package rpc
+
+import (
+	"context"
+	"crypto-trade-client/common/rpc/jsonrpc2"
+	"crypto-trade-client/common/web"
+	webmiddleware "crypto-trade-client/common/web/middleware"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TransferArgs struct {
+	To     string `json:"to"`
+	Amount int    `json:"amount"`
+}
+
+type Wallet struct{}
+
+func (w *Wallet) Balance(ctx context.Context, address string) (int, error) {
+	return len(address), nil
+}
+
+func (w *Wallet) Transfer(args TransferArgs) (string, error) {
+	return args.To + ":" + strings.Repeat("x", args.Amount), nil
+}
+
+func (w *Wallet) Send(to string, amount int, memo *string) (string, error) {
+	if memo != nil {
+		return to + ":" + *memo, nil
+	}
+	return to, nil
+}
+
+func (w *Wallet) Ping() error {
+	return nil
+}
+
+func (w *Wallet) Broke() error {
+	return jsonrpc2.NewError(-32010, "insufficient funds")
+}
+
+func (w *Wallet) Plain() error {
+	return errors.New("boom")
+}
+
+func (w *Wallet) Panic() (int, error) {
+	panic("oops")
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	registry := &ServiceRegistry{}
+	require.NoError(t, registry.Register(&Wallet{}))
+	require.NoError(t, registry.RegisterParamNames("wallet_send", "to", "amount", "memo"))
+	assert.Error(t, registry.RegisterParamNames("wallet_send", "to"))
+
+	server := NewServer(registry)
+	router := gin.New()
+	router.POST("/rpc", server.HandleHTTP())
+	router.GET("/rpc/ws", server.HandleWebSocket())
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func Test_Server_HTTP(t *testing.T) {
+	ts := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   string
+	}{
+		{"positional params", `{"jsonrpc":"2.0","id":1,"method":"wallet_balance","params":["abc"]}`,
+			200, `{"jsonrpc":"2.0","id":1,"result":3}`},
+		{"dot separator and service case", `{"jsonrpc":"2.0","id":"a","method":"Wallet.Balance","params":["ab"]}`,
+			200, `{"jsonrpc":"2.0","id":"a","result":2}`},
+		{"object params of a single argument", `{"jsonrpc":"2.0","id":2,"method":"wallet_transfer","params":{"to":"bob","amount":2}}`,
+			200, `{"jsonrpc":"2.0","id":2,"result":"bob:xx"}`},
+		{"named params", `{"jsonrpc":"2.0","id":3,"method":"wallet_send","params":{"amount":1,"to":"bob","memo":"hi"}}`,
+			200, `{"jsonrpc":"2.0","id":3,"result":"bob:hi"}`},
+		{"optional trailing param", `{"jsonrpc":"2.0","id":4,"method":"wallet_send","params":["bob",1]}`,
+			200, `{"jsonrpc":"2.0","id":4,"result":"bob"}`},
+		{"no result", `{"jsonrpc":"2.0","id":5,"method":"wallet_ping"}`,
+			200, `{"jsonrpc":"2.0","id":5,"result":null}`},
+		{"notification", `{"jsonrpc":"2.0","method":"wallet_ping"}`,
+			204, ``},
+		{"parse error", `{"jsonrpc":"2.0",`,
+			200, `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"JSON RPC parse error"}}`},
+		{"invalid request", `{"jsonrpc":"1.0","id":6,"method":"wallet_ping"}`,
+			200, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"JSON RPC invalid request: invalid message version tag 1.0 expected 2.0"}}`},
+		{"empty batch", `[]`,
+			200, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"JSON RPC invalid request: empty batch"}}`},
+		{"method not found", `{"jsonrpc":"2.0","id":7,"method":"wallet_steal"}`,
+			200, `{"jsonrpc":"2.0","id":7,"error":{"code":-32601,"message":"JSON RPC method not found: \"wallet_steal\""}}`},
+		{"too many params", `{"jsonrpc":"2.0","id":8,"method":"wallet_balance","params":["a","b"]}`,
+			200, `{"jsonrpc":"2.0","id":8,"error":{"code":-32602,"message":"JSON RPC invalid params: too many params, expected at most 1"}}`},
+		{"missing param", `{"jsonrpc":"2.0","id":9,"method":"wallet_balance","params":[]}`,
+			200, `{"jsonrpc":"2.0","id":9,"error":{"code":-32602,"message":"JSON RPC invalid params: missing param 0"}}`},
+		{"unknown named param", `{"jsonrpc":"2.0","id":10,"method":"wallet_send","params":{"to":"bob","amount":1,"fee":1}}`,
+			200, `{"jsonrpc":"2.0","id":10,"error":{"code":-32602,"message":"JSON RPC invalid params: unknown param fee"}}`},
+		{"coded error", `{"jsonrpc":"2.0","id":11,"method":"wallet_broke"}`,
+			200, `{"jsonrpc":"2.0","id":11,"error":{"code":-32010,"message":"insufficient funds"}}`},
+		{"plain error", `{"jsonrpc":"2.0","id":12,"method":"wallet_plain"}`,
+			200, `{"jsonrpc":"2.0","id":12,"error":{"code":-32001,"message":"boom"}}`},
+		{"panic", `{"jsonrpc":"2.0","id":13,"method":"wallet_panic"}`,
+			200, `{"jsonrpc":"2.0","id":13,"error":{"code":-32603,"message":"JSON RPC internal error: method panicked"}}`},
+		{"batch", `[
+				{"jsonrpc":"2.0","id":1,"method":"wallet_balance","params":["abcd"]},
+				{"jsonrpc":"2.0","method":"wallet_ping"},
+				1,
+				{"jsonrpc":"2.0","id":2,"method":"wallet_steal"}
+			]`,
+			200, `[
+				{"jsonrpc":"2.0","id":1,"result":4},
+				{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"JSON RPC invalid request: unmarshaling jsonrpc message: json: cannot unmarshal number into Go value of type jsonrpc2.wireCombined"}},
+				{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"JSON RPC method not found: \"wallet_steal\""}}
+			]`},
+		{"batch of notifications", `[{"jsonrpc":"2.0","method":"wallet_ping"},{"jsonrpc":"2.0","method":"wallet_ping"}]`,
+			204, ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(ts.URL+"/rpc", "application/json", strings.NewReader(tt.body))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.status, resp.StatusCode)
+			if tt.want == "" {
+				assert.Empty(t, body)
+				return
+			}
+			assert.JSONEq(t, tt.want, string(body))
+		})
+	}
+}
+
+type walletSvc struct {
+	Balance  func(string) (int, error)
+	Transfer func(TransferArgs) (string, error) `container:"object"`
+	Broke    func() (int, error)
+	NewBatch BatchFactory
+}
+
+func (s *walletSvc) MethodNamingConvention() NamingConvention {
+	return CamelCase
+}
+
+func (s *walletSvc) Namespace() string {
+	return "wallet"
+}
+
+func (s *walletSvc) NamespaceSeparator() string {
+	return "_"
+}
+
+func Test_Server_Clients(t *testing.T) {
+	ts := newTestServer(t)
+
+	var s walletSvc
+	require.NoError(t, NewClient(context.Background(), ts.URL+"/rpc", "test", &s, map[string]string{}))
+	balance, err := s.Balance("abc")
+	require.NoError(t, err)
+	assert.Equal(t, 3, balance)
+	result, err := s.Transfer(TransferArgs{To: "bob", Amount: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "bob:x", result)
+	_, err = s.Broke()
+	assert.EqualError(t, err, "insufficient funds")
+
+	batch := s.NewBatch()
+	balances := make([]int, 3)
+	for i := range balances {
+		batch.Queue("Balance", &balances[i], strings.Repeat("a", i))
+	}
+	require.NoError(t, batch.Send(context.Background()))
+	assert.Equal(t, []int{0, 1, 2}, balances)
+
+	var ws walletSvc
+	closer, err := NewWebSocketClient(context.Background(), "ws"+strings.TrimPrefix(ts.URL, "http")+"/rpc/ws", "test", &ws, nil)
+	require.NoError(t, err)
+	defer closer()
+	balance, err = ws.Balance("abcde")
+	require.NoError(t, err)
+	assert.Equal(t, 5, balance)
+	_, err = ws.Broke()
+	assert.EqualError(t, err, "insufficient funds")
+}
+
+type upstreamSvc struct {
+	Height func(ctx context.Context) (int, error)
+}
+
+// Relay answers with the height of the upstream node
+type Relay struct {
+	upstream *upstreamSvc
+}
+
+func (r *Relay) Height(ctx context.Context) (int, error) {
+	return r.upstream.Height(ctx)
+}
+
+func Test_Server_TracePropagation(t *testing.T) {
+	received := make(chan string, 1)
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(web.HdrRequestID)
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":42}`))
+	}))
+	defer node.Close()
+
+	var upstream upstreamSvc
+	require.NoError(t, NewClient(context.Background(), node.URL, "node", &upstream, map[string]string{}))
+
+	gin.SetMode(gin.TestMode)
+	registry := &ServiceRegistry{}
+	require.NoError(t, registry.Register(&Relay{upstream: &upstream}))
+	router := gin.New()
+	router.Use(webmiddleware.HcLogger(false, hclog.NewNullLogger()))
+	router.POST("/rpc", NewServer(registry).HandleHTTP())
+	router.GET("/rpc/ws", NewServer(registry).HandleWebSocket())
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	post := func(traceID string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/rpc", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"relay_height"}`))
+		require.NoError(t, err)
+		if traceID != "" {
+			req.Header.Set(web.HdrRequestID, traceID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":42}`, string(body))
+		return resp
+	}
+
+	resp := post("payout-42")
+	assert.Equal(t, "payout-42", resp.Header.Get(web.HdrRequestID))
+	assert.Equal(t, "payout-42", <-received)
+
+	// generated by the middleware
+	resp = post("")
+	traceID := resp.Header.Get(web.HdrRequestID)
+	assert.Len(t, traceID, 32)
+	assert.Equal(t, traceID, <-received)
+
+	// the calls of a websocket connection carry the trace id of the upgrade request
+	header := http.Header{}
+	header.Set(web.HdrRequestID, "stream-7")
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/rpc/ws", header)
+	require.NoError(t, err)
+	defer ws.Close()
+	for id := 1; id <= 2; id++ {
+		require.NoError(t, ws.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": "relay_height"}))
+		var resp struct {
+			Result int `json:"result"`
+		}
+		require.NoError(t, ws.ReadJSON(&resp))
+		assert.Equal(t, 42, resp.Result)
+		assert.Equal(t, "stream-7", <-received)
+	}
+}
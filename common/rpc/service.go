@@ -19,11 +19,14 @@ where T1 and T2 can be marshalled by json encoding.
 package rpc
 
 import (
+	"bytes"
 	"context"
 	"crypto-trade-client/common/rpc/jsonrpc2"
+	"crypto-trade-client/common/web"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/hashicorp/go-hclog"
 	"go/token"
 	"reflect"
 	"runtime"
@@ -36,15 +39,19 @@ const (
 )
 
 type ServiceRegistry struct {
-	serviceMap sync.Map // map[string]*service
+	serviceMap   sync.Map // map[string]*service
+	interceptors []Interceptor
+	log          hclog.Logger
 }
 
 type methodType struct {
-	method    reflect.Method
-	ArgType   []reflect.Type
-	ReplyType reflect.Type
-	hasCtx    bool
-	errPos    int // err return idx, of -1 when method cannot return error
+	method       reflect.Method
+	ArgType      []reflect.Type
+	ReplyType    reflect.Type
+	ParamNames   []string // names of the arguments for object params, see RegisterParamNames
+	hasCtx       bool
+	errPos       int           // err return idx, of -1 when method cannot return error
+	interceptors []Interceptor // interceptors of this method only, see UseFor
 }
 
 type service struct {
@@ -85,12 +92,85 @@ func (registry *ServiceRegistry) register(rcvr interface{}, name string, useName
 		return errors.New("rpc: service has not valid methods")
 	}
 
-	if _, dup := registry.serviceMap.LoadOrStore(sname, s); dup {
+	// services are looked up case-insensitively, as the methods
+	if _, dup := registry.serviceMap.LoadOrStore(strings.ToLower(sname), s); dup {
 		return errors.New("rpc: service already defined: " + sname)
 	}
 	return nil
 }
 
+// WithLogger sets the logger of the panics of the methods
+func (registry *ServiceRegistry) WithLogger(logger hclog.Logger) *ServiceRegistry {
+	registry.log = logger
+	return registry
+}
+
+func (registry *ServiceRegistry) logger() hclog.Logger {
+	if registry.log == nil {
+		return hclog.L().Named("jsonrpc2.service")
+	}
+	return registry.log
+}
+
+// Use adds interceptors wrapping the calls of all the methods, the first added is the outermost.
+// It must be called before serving.
+func (registry *ServiceRegistry) Use(interceptors ...Interceptor) {
+	registry.interceptors = append(registry.interceptors, interceptors...)
+}
+
+// UseFor adds interceptors wrapping the calls of a registered method, e.g. "wallet_transfer".
+// They run inside the interceptors added by Use. It must be called before serving.
+func (registry *ServiceRegistry) UseFor(method string, interceptors ...Interceptor) error {
+	_, m, err := registry.lookup(method)
+	if err != nil {
+		return fmt.Errorf("rpc: method %s is not registered", method)
+	}
+	m.interceptors = append(m.interceptors, interceptors...)
+	return nil
+}
+
+// RegisterParamNames names the arguments of a registered method, e.g. "wallet_transfer", so that it can be
+// called with object params {"to": ..., "amount": ...} in addition to positional params.
+// Methods with a single argument take object params without names, the object is unmarshalled into the argument.
+// It must be called before serving.
+func (registry *ServiceRegistry) RegisterParamNames(method string, names ...string) error {
+	_, m, err := registry.lookup(method)
+	if err != nil {
+		return fmt.Errorf("rpc: method %s is not registered", method)
+	}
+	if len(names) != len(m.ArgType) {
+		return fmt.Errorf("rpc: method %s takes %d arguments, got %d names", method, len(m.ArgType), len(names))
+	}
+	m.ParamNames = names
+	return nil
+}
+
+// lookup resolves `service_method`, `service.method` or `method` of the default service
+func (registry *ServiceRegistry) lookup(method string) (*service, *methodType, error) {
+	nsm := stringSplitter(method, "._")
+	var sname, mname string
+	switch len(nsm) {
+	case 1:
+		sname = defaultService
+		mname = nsm[0]
+	case 2:
+		sname = nsm[0]
+		mname = nsm[1]
+	default:
+		return nil, nil, jsonrpc2.ErrNotHandled
+	}
+	svci, ok := registry.serviceMap.Load(strings.ToLower(sname))
+	if !ok {
+		return nil, nil, jsonrpc2.ErrNotHandled
+	}
+	svc := svci.(*service)
+	m := svc.method[strings.ToLower(mname)]
+	if m == nil {
+		return nil, nil, jsonrpc2.ErrNotHandled
+	}
+	return svc, m, nil
+}
+
 // suitableMethods returns suitable Rpc methods of typ, it will report
 // error using log if reportErr is true.
 func suitableMethods(typ reflect.Type) map[string]*methodType {
@@ -173,76 +253,145 @@ func stringSplitter(s string, splits string) []string {
 	return strings.FieldsFunc(s, splitter)
 }
 
-func (h *serviceHandler) Handle(ctx context.Context, r *jsonrpc2.Request) (interface{}, error) {
-	nsm := stringSplitter(r.Method, "._")
-	var sname, mname string
-	if len(nsm) == 2 {
-		sname = nsm[0]
-		mname = nsm[1]
-	} else {
-		sname = defaultService
-		mname = nsm[0]
-	}
-	svci, ok := h.registry.serviceMap.Load(sname)
-	if !ok {
-		return nil, jsonrpc2.ErrNotHandled
-	}
-	svc := svci.(*service)
-	m := svc.method[strings.ToLower(mname)]
-	if m == nil {
-		return nil, jsonrpc2.ErrNotHandled
+// Handle calls the method of the request through the interceptors. The errors carry the JSON-RPC codes:
+// ErrNotHandled for an unknown method, ErrInvalidParams for params not matching the arguments,
+// ErrInternal for a panic, the errors returned by the method keep their code or get ErrUnknown's.
+func (h *serviceHandler) Handle(ctx context.Context, r *jsonrpc2.Request) (res interface{}, errRes error) {
+	svc, m, err := h.registry.lookup(r.Method)
+	if err != nil {
+		return nil, err
 	}
 
-	args := make([]reflect.Value, 0, 4)
+	// a panic of the method or of an interceptor fails the call only
+	defer func() {
+		if err := recover(); err != nil {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			web.TraceLogger(ctx, h.registry.logger()).Error("rpc method panicked", "method", r.Method, "panic", err, "stack", string(buf))
+			res, errRes = nil, fmt.Errorf("%w: method panicked", jsonrpc2.ErrInternal)
+		}
+	}()
+
+	info := &CallInfo{
+		Method:  r.Method,
+		Service: svc.name,
+		Name:    m.method.Name,
+		Params:  r.Params,
+		IsCall:  r.IsCall(),
+	}
+	invoke := func(ctx context.Context) (interface{}, error) {
+		params, err := m.parseParams(r.Params)
+		if err != nil {
+			return nil, err
+		}
 
-	args = append(args, svc.rcvr)
+		args := make([]reflect.Value, 0, 2+len(params))
+		args = append(args, svc.rcvr)
+		if m.hasCtx {
+			args = append(args, reflect.ValueOf(ctx))
+		}
+		args = append(args, params...)
 
-	if m.hasCtx {
-		args = append(args, reflect.ValueOf(ctx))
+		return m.call(args)
 	}
 
-	if len(m.ArgType) > 0 {
+	return chain(h.registry.interceptors, m.interceptors, info, invoke)(ctx)
+}
+
+// parseParams unmarshals positional params `[...]` or named params `{...}` into the arguments.
+// Missing trailing arguments of pointer, slice, map or interface type are nil.
+func (m *methodType) parseParams(raw json.RawMessage) ([]reflect.Value, error) {
+	values := make([]reflect.Value, len(m.ArgType))
+	raw = bytes.TrimSpace(raw)
+
+	switch {
+	case len(raw) == 0 || bytes.Equal(raw, []byte("null")):
+		// no params
+	case raw[0] == '[':
 		var params []json.RawMessage
-		d, _ := r.Params.MarshalJSON()
-		if err := json.Unmarshal(d, &params); err != nil {
-			return nil, jsonrpc2.ErrParse
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, fmt.Errorf("%w: %v", jsonrpc2.ErrInvalidParams, err)
 		}
-
-		for i, at := range m.ArgType {
-			argv := reflect.New(at)
-			raw := params[i]
-			d1, _ := raw.MarshalJSON()
-			if err := json.Unmarshal(d1, argv.Interface()); err != nil {
-				return nil, jsonrpc2.ErrParse
+		if len(params) > len(m.ArgType) {
+			return nil, fmt.Errorf("%w: too many params, expected at most %d", jsonrpc2.ErrInvalidParams, len(m.ArgType))
+		}
+		for i, param := range params {
+			v, err := unmarshalArg(m.ArgType[i], param)
+			if err != nil {
+				return nil, fmt.Errorf("%w: param %d: %v", jsonrpc2.ErrInvalidParams, i, err)
 			}
-
-			args = append(args, argv.Elem())
+			values[i] = v
+		}
+	case raw[0] == '{':
+		switch {
+		case len(m.ParamNames) > 0:
+			var params map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &params); err != nil {
+				return nil, fmt.Errorf("%w: %v", jsonrpc2.ErrInvalidParams, err)
+			}
+			for i, name := range m.ParamNames {
+				param, ok := params[name]
+				if !ok {
+					continue
+				}
+				delete(params, name)
+				v, err := unmarshalArg(m.ArgType[i], param)
+				if err != nil {
+					return nil, fmt.Errorf("%w: param %s: %v", jsonrpc2.ErrInvalidParams, name, err)
+				}
+				values[i] = v
+			}
+			for name := range params {
+				return nil, fmt.Errorf("%w: unknown param %s", jsonrpc2.ErrInvalidParams, name)
+			}
+		case len(m.ArgType) == 1:
+			v, err := unmarshalArg(m.ArgType[0], raw)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", jsonrpc2.ErrInvalidParams, err)
+			}
+			values[0] = v
+		default:
+			return nil, fmt.Errorf("%w: the method takes positional params", jsonrpc2.ErrInvalidParams)
 		}
+	default:
+		return nil, fmt.Errorf("%w: params must be an array or an object", jsonrpc2.ErrInvalidParams)
 	}
 
-	return m.call(ctx, args)
+	for i, v := range values {
+		if v.IsValid() {
+			continue
+		}
+		switch m.ArgType[i].Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+			values[i] = reflect.Zero(m.ArgType[i])
+		default:
+			return nil, fmt.Errorf("%w: missing param %d", jsonrpc2.ErrInvalidParams, i)
+		}
+	}
+	return values, nil
 }
 
-func (m *methodType) call(ctx context.Context, argv []reflect.Value) (res interface{}, errRes error) {
-	function := m.method.Func
+func unmarshalArg(typ reflect.Type, raw json.RawMessage) (reflect.Value, error) {
+	argv := reflect.New(typ)
+	if err := json.Unmarshal(raw, argv.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	return argv.Elem(), nil
+}
 
-	// Catch panic while running the callback.
-	defer func() {
-		if err := recover(); err != nil {
-			const size = 64 << 10
-			buf := make([]byte, size)
-			buf = buf[:runtime.Stack(buf, false)]
-		}
-	}()
+// nullResult is the result of the methods returning only an error, a call must have a result
+var nullResult = json.RawMessage("null")
 
-	results := function.Call(argv)
-	if len(results) == 0 {
-		return nil, nil
-	}
+func (m *methodType) call(argv []reflect.Value) (interface{}, error) {
+	results := m.method.Func.Call(argv)
 	if m.errPos >= 0 && !results[m.errPos].IsNil() {
 		// Method has returned non-nil error value.
 		err := results[m.errPos].Interface().(error)
-		return reflect.Value{}, err
+		return nil, err
+	}
+	if m.ReplyType == nil {
+		return nullResult, nil
 	}
 	return results[0].Interface(), nil
 }
@@ -0,0 +1,465 @@
+package rpc
+
+import (
+	"context"
+	"crypto-trade-client/common/cache"
+	"crypto-trade-client/common/rpc/jsonrpc2"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	wsMinReconnectDelay = time.Second
+	wsMaxReconnectDelay = 30 * time.Second
+	wsResubscribeWait   = 10 * time.Second
+	wsUnsubscribeWait   = 5 * time.Second
+
+	// subscriptionBuffer is the capacity of the channels returned by subscriptions,
+	// the connection stops delivering notifications while one of them is full
+	subscriptionBuffer = 128
+)
+
+var (
+	// ErrNotConnected is returned by the calls of a websocket client while it is reconnecting
+	ErrNotConnected = errors.New("websocket is not connected")
+	// ErrClientClosed is returned by the calls of a closed websocket client
+	ErrClientClosed = errors.New("rpc client is closed")
+)
+
+// NewWebSocketClient creates new jsonrpc 2.0 client over a WebSocket connection to the ws:// or wss:// endpoint.
+//
+// handler must be pointer to a struct with function fields, as for NewClient. In addition the fields
+// may return a receive channel to subscribe to notifications, e.g. eth_subscribe:
+//
+//	type EthSubscription struct {
+//		NewHeads func(context.Context) (<-chan Header, error)
+//		Logs     func(context.Context, FilterOption) (<-chan types.Log, error)
+//	}
+//
+// calls `<namespace>_subscribe` with the method name followed by the arguments as params, e.g.
+// ["logs", {...}]. The channel receives the results of the notifications until the context is done,
+// then the subscription is cancelled with `<namespace>_unsubscribe` and the channel is closed.
+//
+// The connection is re-established with backoff when it is lost and the active subscriptions
+// are subscribed again, the notifications sent meanwhile are lost. A subscription which can not be
+// subscribed again gets its channel closed.
+//
+// Returned value closes the client connection and all its subscriptions.
+func NewWebSocketClient(ctx context.Context, endpoint, clientName string, handler interface{}, headers map[string]string) (ClientCloser, error) {
+	l := hclog.L().Named("jsonrpc2." + clientName)
+
+	header := http.Header{}
+	for k, v := range headers {
+		header.Set(k, v)
+	}
+
+	ws := newWsTransport(jsonrpc2.WebSocketDialer(endpoint, header), l)
+	if err := ws.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	client := &Client{name: clientName, log: l, ws: ws, cacheStorage: cache.NewCache()}
+	if err := client.provide(handler); err != nil {
+		ws.close()
+		return nil, err
+	}
+
+	return ws.close, nil
+}
+
+// wsTransport sends the calls of a Client over a jsonrpc2.Connection and keeps it connected
+type wsTransport struct {
+	dialer jsonrpc2.Dialer
+	log    hclog.Logger
+
+	lock     sync.Mutex
+	conn     *jsonrpc2.Connection // nil while reconnecting
+	connLost chan struct{}        // closed when conn is lost
+	closed   bool
+	closing  chan struct{}
+
+	// subsLock is held while subscribing, so that the notifications of a new subscription
+	// wait for it to be registered
+	subsLock sync.Mutex
+	subs     map[string]*subscription // by server subscription id
+	active   map[*subscription]struct{}
+}
+
+func newWsTransport(dialer jsonrpc2.Dialer, log hclog.Logger) *wsTransport {
+	return &wsTransport{
+		dialer:  dialer,
+		log:     log,
+		closing: make(chan struct{}),
+		subs:    make(map[string]*subscription),
+		active:  make(map[*subscription]struct{}),
+	}
+}
+
+// boundDialer dials with its own context, the connection must not end with the context of a call
+type boundDialer struct {
+	ctx    context.Context
+	dialer jsonrpc2.Dialer
+}
+
+func (d boundDialer) Dial(context.Context) (io.ReadWriteCloser, error) {
+	return d.dialer.Dial(d.ctx)
+}
+
+func (t *wsTransport) connect(ctx context.Context) error {
+	conn, err := jsonrpc2.Dial(context.Background(), boundDialer{ctx: ctx, dialer: t.dialer}, jsonrpc2.ConnectionOptions{
+		Framer:  jsonrpc2.RawFramer(),
+		Handler: jsonrpc2.HandlerFunc(t.handleNotification),
+	})
+	if err != nil {
+		return err
+	}
+
+	lost := make(chan struct{})
+	t.lock.Lock()
+	if t.closed {
+		t.lock.Unlock()
+		_ = conn.Close()
+		return ErrClientClosed
+	}
+	t.conn = conn
+	t.connLost = lost
+	t.lock.Unlock()
+
+	go t.monitor(conn, lost)
+	return nil
+}
+
+// monitor waits for the connection to end and reconnects unless the client is closed
+func (t *wsTransport) monitor(conn *jsonrpc2.Connection, lost chan struct{}) {
+	err := conn.Wait()
+	close(lost)
+
+	t.lock.Lock()
+	if t.conn == conn {
+		t.conn = nil
+	}
+	closed := t.closed
+	t.lock.Unlock()
+	if closed {
+		return
+	}
+
+	t.log.Warn("websocket connection lost", "err", err)
+	_ = conn.Close()
+
+	delay := wsMinReconnectDelay
+	for {
+		select {
+		case <-t.closing:
+			return
+		case <-time.After(delay):
+		}
+		if err = t.connect(context.Background()); err == nil {
+			break
+		}
+		if errors.Is(err, ErrClientClosed) {
+			return
+		}
+		t.log.Warn("websocket reconnect failed", "err", err, "retryIn", delay)
+		delay *= 2
+		if delay > wsMaxReconnectDelay {
+			delay = wsMaxReconnectDelay
+		}
+	}
+
+	t.log.Info("websocket reconnected")
+	t.resubscribe()
+}
+
+func (t *wsTransport) close() {
+	t.lock.Lock()
+	if t.closed {
+		t.lock.Unlock()
+		return
+	}
+	t.closed = true
+	close(t.closing)
+	conn := t.conn
+	t.lock.Unlock()
+
+	if conn != nil {
+		if err := conn.Close(); err != nil {
+			t.log.Debug("closing websocket connection", "err", err)
+		}
+	}
+
+	t.subsLock.Lock()
+	subs := make([]*subscription, 0, len(t.active))
+	for sub := range t.active {
+		subs = append(subs, sub)
+	}
+	t.subs = make(map[string]*subscription)
+	t.active = make(map[*subscription]struct{})
+	t.subsLock.Unlock()
+	for _, sub := range subs {
+		sub.close()
+	}
+}
+
+func (t *wsTransport) connection() (*jsonrpc2.Connection, chan struct{}, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.closed {
+		return nil, nil, ErrClientClosed
+	}
+	if t.conn == nil {
+		return nil, nil, ErrNotConnected
+	}
+	return t.conn, t.connLost, nil
+}
+
+// await waits for the result of the call, failing when the connection is lost meanwhile
+func (t *wsTransport) await(ctx context.Context, lost chan struct{}, call *jsonrpc2.AsyncCall, result interface{}) error {
+	awaitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-lost:
+			cancel()
+		case <-awaitCtx.Done():
+		}
+	}()
+
+	err := call.Await(awaitCtx, result)
+	if err != nil && ctx.Err() == nil && awaitCtx.Err() != nil {
+		return ErrNotConnected
+	}
+	return err
+}
+
+func (t *wsTransport) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	conn, lost, err := t.connection()
+	if err != nil {
+		return err
+	}
+	return t.await(ctx, lost, conn.Call(ctx, method, params), result)
+}
+
+// send sends the request, its id is replaced by one of the connection
+func (t *wsTransport) send(ctx context.Context, req *jsonrpc2.Request) (*jsonrpc2.Response, error) {
+	var result json.RawMessage
+	err := t.call(ctx, req.Method, rawParams(req.Params), &result)
+	if isTransportError(err) {
+		return nil, err
+	}
+	return &jsonrpc2.Response{ID: req.ID, Result: result, Error: err}, nil
+}
+
+// sendAll pipelines the requests on the connection, it is the websocket counterpart of a batch
+func (t *wsTransport) sendAll(ctx context.Context, msgs []jsonrpc2.Message) ([]jsonrpc2.Message, error) {
+	conn, lost, err := t.connection()
+	if err != nil {
+		return nil, err
+	}
+
+	calls := make([]*jsonrpc2.AsyncCall, len(msgs))
+	for i, msg := range msgs {
+		req := msg.(*jsonrpc2.Request)
+		calls[i] = conn.Call(ctx, req.Method, rawParams(req.Params))
+	}
+
+	responses := make([]jsonrpc2.Message, len(msgs))
+	for i, call := range calls {
+		var result json.RawMessage
+		err = t.await(ctx, lost, call, &result)
+		if isTransportError(err) {
+			return nil, err
+		}
+		responses[i] = &jsonrpc2.Response{ID: msgs[i].(*jsonrpc2.Request).ID, Result: result, Error: err}
+	}
+	return responses, nil
+}
+
+func rawParams(params json.RawMessage) interface{} {
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+func isTransportError(err error) bool {
+	return errors.Is(err, ErrNotConnected) || errors.Is(err, ErrClientClosed) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// subscription is an active subscription of a channel returning rpcFunc
+type subscription struct {
+	fn     *rpcFunc
+	params []interface{}
+	id     string // server subscription id, guarded by wsTransport.subsLock
+
+	lock      sync.RWMutex // held for writing when closing ch
+	ch        reflect.Value
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (t *wsTransport) subscribe(ctx context.Context, sub *subscription) error {
+	t.subsLock.Lock()
+	defer t.subsLock.Unlock()
+
+	var id json.RawMessage
+	if err := t.call(ctx, sub.fn.name, sub.params, &id); err != nil {
+		return err
+	}
+	if len(id) == 0 || string(id) == "null" {
+		return fmt.Errorf("%s returned no subscription id", sub.fn.name)
+	}
+
+	sub.id = string(id)
+	t.subs[sub.id] = sub
+	t.active[sub] = struct{}{}
+	return nil
+}
+
+func (t *wsTransport) unsubscribe(sub *subscription) {
+	t.subsLock.Lock()
+	_, active := t.active[sub]
+	delete(t.active, sub)
+	delete(t.subs, sub.id)
+	id := sub.id
+	t.subsLock.Unlock()
+
+	if active {
+		ctx, cancel := context.WithTimeout(context.Background(), wsUnsubscribeWait)
+		defer cancel()
+		if err := t.call(ctx, sub.fn.unsubscribe, []json.RawMessage{json.RawMessage(id)}, nil); err != nil {
+			t.log.Debug("unsubscribe failed", "method", sub.fn.subscription, "id", id, "err", err)
+		}
+	}
+	sub.close()
+}
+
+// resubscribe subscribes the active subscriptions again on a new connection
+func (t *wsTransport) resubscribe() {
+	t.subsLock.Lock()
+	subs := make([]*subscription, 0, len(t.active))
+	for sub := range t.active {
+		subs = append(subs, sub)
+		delete(t.subs, sub.id)
+	}
+	t.subsLock.Unlock()
+
+	for _, sub := range subs {
+		ctx, cancel := context.WithTimeout(context.Background(), wsResubscribeWait)
+		err := t.subscribe(ctx, sub)
+		cancel()
+		switch {
+		case err == nil:
+			t.log.Debug("resubscribed", "method", sub.fn.subscription, "id", sub.id)
+		case isTransportError(err):
+			// lost again, the next reconnection subscribes again
+			return
+		default:
+			t.log.Warn("resubscribe failed", "method", sub.fn.subscription, "err", err)
+			t.subsLock.Lock()
+			delete(t.active, sub)
+			t.subsLock.Unlock()
+			sub.close()
+		}
+	}
+}
+
+// handleNotification delivers the subscription notifications, {"subscription": id, "result": value}
+func (t *wsTransport) handleNotification(ctx context.Context, req *jsonrpc2.Request) (interface{}, error) {
+	if req.IsCall() {
+		return nil, jsonrpc2.ErrNotHandled
+	}
+
+	var params struct {
+		Subscription json.RawMessage `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params.Subscription) == 0 {
+		t.log.Trace("ignored notification", "method", req.Method)
+		return nil, nil
+	}
+
+	t.subsLock.Lock()
+	sub := t.subs[string(params.Subscription)]
+	t.subsLock.Unlock()
+	if sub == nil {
+		t.log.Trace("notification of unknown subscription", "id", string(params.Subscription))
+		return nil, nil
+	}
+
+	sub.deliver(params.Result, t.log)
+	return nil, nil
+}
+
+func (s *subscription) deliver(result json.RawMessage, log hclog.Logger) {
+	v := reflect.New(s.ch.Type().Elem())
+	if err := json.Unmarshal(result, v.Interface()); err != nil {
+		log.Warn("unmarshalling notification failed", "method", s.fn.subscription, "message", string(result))
+		return
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	reflect.Select([]reflect.SelectCase{
+		{Dir: reflect.SelectSend, Chan: s.ch, Send: v.Elem()},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(s.done)},
+	})
+}
+
+func (s *subscription) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.lock.Lock()
+		s.ch.Close()
+		s.lock.Unlock()
+	})
+}
+
+func (fn *rpcFunc) handleSubscribe(ctx context.Context, args []reflect.Value) []reflect.Value {
+	ws := fn.client.ws
+	if ws == nil {
+		return fn.processError(fmt.Errorf("%s needs a websocket client", fn.subscription))
+	}
+
+	params := []interface{}{fn.subscription}
+	for _, arg := range args {
+		params = append(params, arg.Interface())
+	}
+	outType := fn.ftyp.Out(fn.valOut)
+	sub := &subscription{
+		fn:     fn,
+		params: params,
+		ch:     reflect.MakeChan(reflect.ChanOf(reflect.BothDir, outType.Elem()), subscriptionBuffer),
+		done:   make(chan struct{}),
+	}
+
+	if err := ws.subscribe(ctx, sub); err != nil {
+		if isTransportError(err) {
+			return fn.processError(err)
+		}
+		return fn.processResponse(err, reflect.Zero(outType))
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			ws.unsubscribe(sub)
+		case <-sub.done:
+		}
+	}()
+
+	return fn.processResponse(nil, sub.ch.Convert(outType))
+}
@@ -0,0 +1,271 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type wsSvc struct {
+	Inc      func(int) (int, error)
+	Fail     func() (int, error)
+	Counter  func(context.Context, int) (<-chan int, error)
+	NewBatch BatchFactory
+}
+
+func (s *wsSvc) MethodNamingConvention() NamingConvention {
+	return CamelCase
+}
+
+func (s *wsSvc) Namespace() string {
+	return "test"
+}
+
+func (s *wsSvc) NamespaceSeparator() string {
+	return "_"
+}
+
+// wsServer serves test_inc and counter subscriptions, which notify start, start+1... every 5ms
+type wsServer struct {
+	server *httptest.Server
+
+	lock         sync.Mutex
+	conns        map[*websocket.Conn]struct{}
+	subscribed   int
+	unsubscribed []string
+}
+
+func newWsServer(t *testing.T) *wsServer {
+	s := &wsServer{conns: make(map[*websocket.Conn]struct{})}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *wsServer) url() string {
+	return "ws" + strings.TrimPrefix(s.server.URL, "http")
+}
+
+// drop closes the connections, as a node restart would
+func (s *wsServer) drop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+}
+
+func (s *wsServer) serve(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	s.lock.Lock()
+	s.conns[conn] = struct{}{}
+	s.lock.Unlock()
+
+	var writeLock sync.Mutex
+	write := func(v interface{}) error {
+		writeLock.Lock()
+		defer writeLock.Unlock()
+		return conn.WriteJSON(v)
+	}
+	stops := make(map[string]chan struct{})
+	defer func() {
+		for _, stop := range stops {
+			close(stop)
+		}
+		s.lock.Lock()
+		delete(s.conns, conn)
+		s.lock.Unlock()
+		_ = conn.Close()
+	}()
+
+	for {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		switch req.Method {
+		case "test_inc":
+			var v int
+			_ = json.Unmarshal(req.Params[0], &v)
+			resp["result"] = v + 1
+		case "test_subscribe":
+			var name string
+			var start int
+			_ = json.Unmarshal(req.Params[0], &name)
+			_ = json.Unmarshal(req.Params[1], &start)
+			s.lock.Lock()
+			s.subscribed++
+			id := fmt.Sprintf("0x%x", s.subscribed)
+			s.lock.Unlock()
+			stop := make(chan struct{})
+			stops[id] = stop
+			resp["result"] = id
+			_ = write(resp)
+			go func() {
+				for i := start; ; i++ {
+					select {
+					case <-stop:
+						return
+					case <-time.After(5 * time.Millisecond):
+					}
+					err := write(map[string]interface{}{
+						"jsonrpc": "2.0",
+						"method":  "test_subscription",
+						"params":  map[string]interface{}{"subscription": id, "result": i},
+					})
+					if err != nil {
+						return
+					}
+				}
+			}()
+			continue
+		case "test_unsubscribe":
+			var id string
+			_ = json.Unmarshal(req.Params[0], &id)
+			if stop, ok := stops[id]; ok {
+				close(stop)
+				delete(stops, id)
+			}
+			s.lock.Lock()
+			s.unsubscribed = append(s.unsubscribed, id)
+			s.lock.Unlock()
+			resp["result"] = true
+		default:
+			resp["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+		}
+		if err := write(resp); err != nil {
+			return
+		}
+	}
+}
+
+func Test_WebSocketClient_Call(t *testing.T) {
+	server := newWsServer(t)
+	var s wsSvc
+	closer, err := NewWebSocketClient(context.Background(), server.url(), "test", &s, nil)
+	require.NoError(t, err)
+	defer closer()
+
+	v, err := s.Inc(41)
+	require.NoError(t, err)
+	assert.Equal(t, 42, v)
+
+	_, err = s.Fail()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "method not found")
+
+	batch, err := NewBatch(&s)
+	require.NoError(t, err)
+	results := make([]int, 3)
+	for i := range results {
+		batch.Queue("Inc", &results[i], i)
+	}
+	require.NoError(t, batch.Send(context.Background()))
+	assert.Equal(t, []int{1, 2, 3}, results)
+
+	closer()
+	_, err = s.Inc(1)
+	assert.ErrorIs(t, err, ErrClientClosed)
+}
+
+func Test_WebSocketClient_Subscribe(t *testing.T) {
+	server := newWsServer(t)
+	var s wsSvc
+	closer, err := NewWebSocketClient(context.Background(), server.url(), "test", &s, nil)
+	require.NoError(t, err)
+	defer closer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := s.Counter(ctx, 100)
+	require.NoError(t, err)
+	for i := 100; i < 105; i++ {
+		assert.Equal(t, i, receive(t, ch))
+	}
+
+	cancel()
+	waitClosed(t, ch)
+	assert.Eventually(t, func() bool {
+		server.lock.Lock()
+		defer server.lock.Unlock()
+		return len(server.unsubscribed) == 1 && server.unsubscribed[0] == "0x1"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_WebSocketClient_Resubscribe(t *testing.T) {
+	server := newWsServer(t)
+	var s wsSvc
+	closer, err := NewWebSocketClient(context.Background(), server.url(), "test", &s, nil)
+	require.NoError(t, err)
+
+	ch, err := s.Counter(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0, receive(t, ch))
+
+	server.drop()
+	// the subscription restarts on the new connection
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case <-deadline:
+			t.Fatal("not resubscribed")
+		case <-ch:
+		}
+		server.lock.Lock()
+		subscribed := server.subscribed
+		server.lock.Unlock()
+		if subscribed == 2 {
+			break
+		}
+	}
+	receive(t, ch)
+
+	v, err := s.Inc(1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, v)
+
+	closer()
+	waitClosed(t, ch)
+}
+
+func receive(t *testing.T, ch <-chan int) int {
+	select {
+	case v, ok := <-ch:
+		require.True(t, ok, "channel closed")
+		return v
+	case <-time.After(3 * time.Second):
+		t.Fatal("no notification")
+		return 0
+	}
+}
+
+func waitClosed(t *testing.T, ch <-chan int) {
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("channel not closed")
+		}
+	}
+}
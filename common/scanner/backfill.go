@@ -0,0 +1,271 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultWorkers is the number of concurrent fetches of Backfill
+	DefaultWorkers = 4
+	// DefaultProgressInterval is the interval between two progress reports of Backfill
+	DefaultProgressInterval = 10 * time.Second
+
+	// backfillAttempts is the number of fetches of a batch before Backfill fails
+	backfillAttempts = 5
+)
+
+// Progress is the state of a Backfill
+type Progress struct {
+	From, To     int64
+	Height       int64 // last handled height
+	Blocks       int64
+	Transactions int64
+	Elapsed      time.Duration
+}
+
+// Percent returns the share of the range handled
+func (p Progress) Percent() float64 {
+	return 100 * float64(p.Blocks) / float64(p.To-p.From+1)
+}
+
+// Rate returns the handled blocks per second
+func (p Progress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Blocks) / p.Elapsed.Seconds()
+}
+
+// Remaining estimates the time to handle the rest of the range
+func (p Progress) Remaining() time.Duration {
+	rate := p.Rate()
+	if rate == 0 {
+		return 0
+	}
+	return time.Duration(float64(p.To-p.Height) / rate * float64(time.Second))
+}
+
+// ProgressHandler is called with the progress of a Backfill at every progress interval, and once done
+type ProgressHandler func(progress Progress)
+
+// WithWorkers sets the number of concurrent fetches of Backfill
+func (s *Scanner) WithWorkers(workers int) *Scanner {
+	if workers > 0 {
+		s.workers = workers
+	}
+	return s
+}
+
+// WithBatchSize sets the number of blocks fetched at once by a Backfill worker, in one request when the
+// BlockSource is a BatchSource. Default is 1.
+func (s *Scanner) WithBatchSize(size int) *Scanner {
+	if size > 0 {
+		s.batchSize = size
+	}
+	return s
+}
+
+// WithProgressInterval sets the interval between two progress reports of Backfill
+func (s *Scanner) WithProgressInterval(interval time.Duration) *Scanner {
+	if interval > 0 {
+		s.progressInterval = interval
+	}
+	return s
+}
+
+// OnProgress adds a handler of the progress of Backfill, the progress is logged anyway
+func (s *Scanner) OnProgress(handler ProgressHandler) *Scanner {
+	s.progressHandlers = append(s.progressHandlers, handler)
+	return s
+}
+
+// batch is a range of heights fetched by a Backfill worker
+type batch struct {
+	index    int
+	from, to int64
+	blocks   []*Block
+	err      error
+}
+
+// Backfill fetches the blocks from one height to another with concurrent workers, and passes them to the
+// handlers in order of height. The blocks within the confirmed height of the chain are passed to the confirmed
+// handlers as well. It neither moves the checkpoint nor the height of Run, and must not run along with it.
+//
+// A fetch is retried a few times, Backfill fails on the first failed handler: the handlers being idempotent,
+// the backfill resumes after the returned progress' height.
+func (s *Scanner) Backfill(ctx context.Context, from, to int64) (Progress, error) {
+	progress := Progress{From: from, To: to, Height: from - 1}
+	if from < 0 || from > to {
+		return progress, fmt.Errorf("invalid range %d-%d", from, to)
+	}
+	head, err := s.source.LatestHeight(ctx)
+	if err != nil {
+		return progress, fmt.Errorf("getting latest height of %s: %w", s.name, err)
+	}
+	if to > head {
+		return progress, fmt.Errorf("range %d-%d is beyond the head %d", from, to, head)
+	}
+	confirmed := int64(-1)
+	if s.gating() {
+		if confirmed, err = s.confirmedTip(ctx, head); err != nil {
+			return progress, fmt.Errorf("getting confirmed height of %s: %w", s.name, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// a slot is taken by a batch from its fetch until its handling, bounding the batches fetched ahead
+	slots := make(chan struct{}, 2*s.workers)
+	results := s.fetchRange(ctx, from, to, slots)
+
+	s.log.Info("backfilling", "from", from, "to", to, "workers", s.workers, "batch", s.batchSize)
+	started := time.Now()
+	ticker := time.NewTicker(s.progressInterval)
+	defer ticker.Stop()
+
+	// the batches are fetched in any order, the handlers see them in order of index
+	pending := make(map[int]*batch)
+	next := 0
+	var parent *Block
+	for progress.Height < to {
+		select {
+		case <-ctx.Done():
+			return progress, ctx.Err()
+		case <-ticker.C:
+			progress.Elapsed = time.Since(started)
+			s.report(progress)
+		case b := <-results:
+			if b.err != nil {
+				return progress, fmt.Errorf("getting blocks %d-%d: %w", b.from, b.to, b.err)
+			}
+			pending[b.index] = b
+			for b = pending[next]; b != nil; b = pending[next] {
+				delete(pending, next)
+				next++
+				<-slots
+				for _, block := range b.blocks {
+					if parent != nil && block.ParentHash != "" && block.ParentHash != parent.Hash {
+						return progress, fmt.Errorf("block %d does not extend block %d, the chain reorganized during the backfill",
+							block.Height, parent.Height)
+					}
+					if err = s.handleBackfilled(ctx, block, block.Height <= confirmed); err != nil {
+						return progress, fmt.Errorf("handling block %d: %w", block.Height, err)
+					}
+					parent = block
+					progress.Height = block.Height
+					progress.Blocks++
+					progress.Transactions += int64(len(block.Transactions))
+				}
+			}
+		}
+	}
+
+	progress.Elapsed = time.Since(started)
+	s.report(progress)
+	return progress, nil
+}
+
+// fetchRange fetches the batches of the range with the workers, a batch is fetched once it takes a slot
+func (s *Scanner) fetchRange(ctx context.Context, from, to int64, slots chan struct{}) <-chan *batch {
+	batches := make(chan *batch)
+	results := make(chan *batch)
+
+	go func() {
+		defer close(batches)
+		size := int64(s.batchSize)
+		for index, height := 0, from; height <= to; index, height = index+1, height+size {
+			b := &batch{index: index, from: height, to: height + size - 1}
+			if b.to > to {
+				b.to = to
+			}
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case batches <- b:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < s.workers; i++ {
+		go func() {
+			for b := range batches {
+				b.blocks, b.err = s.fetchBatch(ctx, b.from, b.to)
+				select {
+				case results <- b:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	return results
+}
+
+// fetchBatch fetches the blocks of the batch, retrying the failed fetches
+func (s *Scanner) fetchBatch(ctx context.Context, from, to int64) ([]*Block, error) {
+	for attempt := 1; ; attempt++ {
+		blocks, err := s.fetchBlocks(ctx, from, to)
+		if err == nil {
+			return blocks, nil
+		}
+		if attempt == backfillAttempts || ctx.Err() != nil {
+			return nil, err
+		}
+		s.log.Warn("getting blocks failed", "from", from, "to", to, "attempt", attempt, "err", err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+func (s *Scanner) fetchBlocks(ctx context.Context, from, to int64) ([]*Block, error) {
+	if source, ok := s.source.(BatchSource); ok && to > from {
+		heights := make([]int64, 0, to-from+1)
+		for height := from; height <= to; height++ {
+			heights = append(heights, height)
+		}
+		blocks, err := source.BlocksByHeight(ctx, heights)
+		if err == nil && len(blocks) != len(heights) {
+			err = fmt.Errorf("got %d blocks for %d heights", len(blocks), len(heights))
+		}
+		return blocks, err
+	}
+
+	blocks := make([]*Block, 0, to-from+1)
+	for height := from; height <= to; height++ {
+		block, err := s.source.BlockByHeight(ctx, height)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", height, err)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+func (s *Scanner) handleBackfilled(ctx context.Context, block *Block, confirmed bool) error {
+	if err := s.handle(ctx, block); err != nil {
+		return err
+	}
+	if confirmed {
+		return s.handleConfirmed(ctx, block)
+	}
+	return nil
+}
+
+func (s *Scanner) report(progress Progress) {
+	s.log.Info("backfill progress", "height", progress.Height, "to", progress.To,
+		"percent", fmt.Sprintf("%.1f", progress.Percent()), "blocks_per_sec", fmt.Sprintf("%.1f", progress.Rate()),
+		"remaining", progress.Remaining().Round(time.Second))
+	for _, h := range s.progressHandlers {
+		h(progress)
+	}
+}
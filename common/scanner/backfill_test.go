@@ -0,0 +1,144 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowSource delays the fetches of the low blocks, so that the workers complete out of order
+type slowSource struct {
+	*fakeSource
+}
+
+func (s slowSource) BlockByHeight(ctx context.Context, height int64) (*Block, error) {
+	time.Sleep(time.Duration(20-height%20) * time.Millisecond)
+	return s.fakeSource.BlockByHeight(ctx, height)
+}
+
+// batchSource counts the batches fetched
+type batchSource struct {
+	*fakeSource
+	lock    sync.Mutex
+	batches [][]int64
+}
+
+func (s *batchSource) BlocksByHeight(ctx context.Context, heights []int64) ([]*Block, error) {
+	s.lock.Lock()
+	s.batches = append(s.batches, heights)
+	s.lock.Unlock()
+	blocks := make([]*Block, 0, len(heights))
+	for _, height := range heights {
+		block, err := s.BlockByHeight(ctx, height)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+func newBackfillScanner(source BlockSource, c *collector) *Scanner {
+	return NewScanner("fake", source).
+		WithLogger(hclog.NewNullLogger()).
+		WithInterval(time.Millisecond).
+		OnBlock(c.onBlock).
+		OnTransaction(c.onTx)
+}
+
+func heights(from, to int64) []int64 {
+	var list []int64
+	for h := from; h <= to; h++ {
+		list = append(list, h)
+	}
+	return list
+}
+
+func Test_Scanner_Backfill(t *testing.T) {
+	c := &collector{}
+	var reports []Progress
+	s := newBackfillScanner(slowSource{newFakeSource(50)}, c).
+		WithWorkers(8).
+		WithBatchSize(3).
+		OnProgress(func(progress Progress) { reports = append(reports, progress) })
+
+	progress, err := s.Backfill(context.Background(), 5, 44)
+	require.NoError(t, err)
+	assert.Equal(t, heights(5, 44), c.heights())
+	assert.Len(t, c.txs, 80)
+	assert.EqualValues(t, 44, progress.Height)
+	assert.EqualValues(t, 40, progress.Blocks)
+	assert.EqualValues(t, 80, progress.Transactions)
+	assert.Equal(t, 100.0, progress.Percent())
+
+	// the last report is the final progress
+	require.NotEmpty(t, reports)
+	assert.Equal(t, progress, reports[len(reports)-1])
+	// the scan state is left to Run
+	assert.EqualValues(t, 0, s.Height())
+}
+
+func Test_Scanner_BackfillBatches(t *testing.T) {
+	source := &batchSource{fakeSource: newFakeSource(20)}
+	c := &collector{}
+	_, err := newBackfillScanner(source, c).WithWorkers(2).WithBatchSize(4).Backfill(context.Background(), 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, heights(1, 10), c.heights())
+	assert.ElementsMatch(t, [][]int64{{1, 2, 3, 4}, {5, 6, 7, 8}, {9, 10}}, source.batches)
+}
+
+func Test_Scanner_BackfillRetries(t *testing.T) {
+	source := newFakeSource(10)
+	source.fail(4, backfillAttempts-1)
+	c := &collector{}
+	_, err := newBackfillScanner(source, c).Backfill(context.Background(), 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, heights(1, 10), c.heights())
+
+	// the blocks before the failed one are handled
+	source.fail(4, backfillAttempts)
+	c = &collector{}
+	progress, err := newBackfillScanner(source, c).WithWorkers(1).Backfill(context.Background(), 1, 10)
+	assert.ErrorContains(t, err, "node unavailable")
+	assert.EqualValues(t, 3, progress.Height)
+	assert.Equal(t, heights(1, 3), c.heights())
+}
+
+func Test_Scanner_BackfillHandlerFailure(t *testing.T) {
+	c := &collector{}
+	s := newBackfillScanner(newFakeSource(10), c).
+		OnBlock(func(ctx context.Context, block *Block) error {
+			if block.Height == 6 {
+				return errors.New("database down")
+			}
+			return nil
+		})
+	progress, err := s.Backfill(context.Background(), 1, 10)
+	assert.ErrorContains(t, err, "handling block 6: database down")
+	assert.EqualValues(t, 5, progress.Height)
+}
+
+func Test_Scanner_BackfillConfirmed(t *testing.T) {
+	e := &events{}
+	s := newConfirmScanner(newFakeSource(10), e).WithConfirmations(3)
+	_, err := s.Backfill(context.Background(), 7, 10)
+	require.NoError(t, err)
+	// the head and its parent are not confirmed yet
+	assert.Equal(t, []string{
+		"block 0x7", "confirmed 0x7", "block 0x8", "confirmed 0x8", "block 0x9", "block 0x10",
+	}, e.get())
+}
+
+func Test_Scanner_BackfillRange(t *testing.T) {
+	s := newBackfillScanner(newFakeSource(10), &collector{})
+	_, err := s.Backfill(context.Background(), 5, 4)
+	assert.ErrorContains(t, err, "invalid range")
+	_, err = s.Backfill(context.Background(), 5, 11)
+	assert.ErrorContains(t, err, "beyond the head 10")
+}
@@ -0,0 +1,135 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Checkpoint is the last fully processed block of a chain
+type Checkpoint struct {
+	Chain     string    `json:"chain"`
+	Height    int64     `json:"height"`
+	Hash      string    `json:"hash"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Recent are the processed blocks below the checkpoint block in the window of recent blocks,
+	// so that a reorg of the checkpoint while the scanner is stopped is rolled back on restart
+	Recent []BlockRef `json:"recent,omitempty"`
+}
+
+// BlockRef identifies a processed block
+type BlockRef struct {
+	Height int64  `json:"height"`
+	Hash   string `json:"hash"`
+}
+
+// CheckpointStore keeps the checkpoints of the scanners, so that a restarted scanner resumes after
+// the last processed block instead of skipping the blocks produced while it was down
+type CheckpointStore interface {
+	// Load returns the checkpoint of the chain, nil when there is none
+	Load(ctx context.Context, chain string) (*Checkpoint, error)
+	// Save replaces the checkpoint of its chain
+	Save(ctx context.Context, checkpoint Checkpoint) error
+}
+
+// FileCheckpointStore keeps the checkpoints of all the chains in a local JSON file. The file is replaced
+// atomically on every save, a crash leaves the previous checkpoints.
+type FileCheckpointStore struct {
+	path string
+
+	lock        sync.Mutex
+	checkpoints map[string]Checkpoint // nil until read
+}
+
+// NewFileCheckpointStore creates a store of the file, which is created by the first save
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+func (s *FileCheckpointStore) Load(ctx context.Context, chain string) (*Checkpoint, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.read(); err != nil {
+		return nil, err
+	}
+	checkpoint, ok := s.checkpoints[chain]
+	if !ok {
+		return nil, nil
+	}
+	return &checkpoint, nil
+}
+
+func (s *FileCheckpointStore) Save(ctx context.Context, checkpoint Checkpoint) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.read(); err != nil {
+		return err
+	}
+	if checkpoint.UpdatedAt.IsZero() {
+		checkpoint.UpdatedAt = time.Now().UTC()
+	}
+
+	checkpoints := make(map[string]Checkpoint, len(s.checkpoints)+1)
+	for chain, c := range s.checkpoints {
+		checkpoints[chain] = c
+	}
+	checkpoints[checkpoint.Chain] = checkpoint
+	if err := s.write(checkpoints); err != nil {
+		return err
+	}
+	s.checkpoints = checkpoints
+	return nil
+}
+
+func (s *FileCheckpointStore) read() error {
+	if s.checkpoints != nil {
+		return nil
+	}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.checkpoints = make(map[string]Checkpoint)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading checkpoints: %v", err)
+	}
+	checkpoints := make(map[string]Checkpoint)
+	if err = json.Unmarshal(data, &checkpoints); err != nil {
+		return fmt.Errorf("invalid checkpoints %s: %v", s.path, err)
+	}
+	s.checkpoints = checkpoints
+	return nil
+}
+
+// write replaces the file through a temporary file, so that it is never left half written
+func (s *FileCheckpointStore) write(checkpoints map[string]Checkpoint) error {
+	data, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(s.path)
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(append(data, '\n')); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
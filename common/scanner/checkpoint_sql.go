@@ -0,0 +1,144 @@
+package scanner
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultCheckpointTable is the table of a SQLCheckpointStore
+const DefaultCheckpointTable = "scanner_checkpoints"
+
+// SQLCheckpointStore keeps the checkpoints in a table of a SQL database, one row per chain:
+//
+//	CREATE TABLE scanner_checkpoints (
+//		chain      VARCHAR(64) PRIMARY KEY,
+//		height     BIGINT NOT NULL,
+//		hash       VARCHAR(128) NOT NULL,
+//		updated_at TIMESTAMP NOT NULL,
+//		recent     TEXT
+//	)
+//
+// The recent column holds the JSON of Checkpoint.Recent, it is added to the tables created before it with
+// ALTER TABLE scanner_checkpoints ADD COLUMN recent TEXT.
+// The statements are portable, the placeholders are ? unless WithDollarPlaceholders is set for PostgreSQL.
+type SQLCheckpointStore struct {
+	db     *sql.DB
+	table  string
+	dollar bool
+}
+
+// NewSQLCheckpointStore creates a store of the database, the driver is registered by the caller
+func NewSQLCheckpointStore(db *sql.DB) *SQLCheckpointStore {
+	return &SQLCheckpointStore{db: db, table: DefaultCheckpointTable}
+}
+
+// WithTable sets the table of the checkpoints
+func (s *SQLCheckpointStore) WithTable(table string) *SQLCheckpointStore {
+	s.table = table
+	return s
+}
+
+// WithDollarPlaceholders uses the $1, $2... placeholders of PostgreSQL
+func (s *SQLCheckpointStore) WithDollarPlaceholders() *SQLCheckpointStore {
+	s.dollar = true
+	return s
+}
+
+// CreateTable creates the table of the checkpoints unless it exists
+func (s *SQLCheckpointStore) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	chain      VARCHAR(64) PRIMARY KEY,
+	height     BIGINT NOT NULL,
+	hash       VARCHAR(128) NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	recent     TEXT
+)`, s.table))
+	return err
+}
+
+func (s *SQLCheckpointStore) Load(ctx context.Context, chain string) (*Checkpoint, error) {
+	checkpoint := Checkpoint{Chain: chain}
+	var recent sql.NullString
+	err := s.db.QueryRowContext(ctx, s.query("SELECT height, hash, updated_at, recent FROM %s WHERE chain = ?"), chain).
+		Scan(&checkpoint.Height, &checkpoint.Hash, &checkpoint.UpdatedAt, &recent)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if recent.String != "" {
+		if err = json.Unmarshal([]byte(recent.String), &checkpoint.Recent); err != nil {
+			return nil, fmt.Errorf("invalid recent blocks of %s: %v", chain, err)
+		}
+	}
+	return &checkpoint, nil
+}
+
+func (s *SQLCheckpointStore) Save(ctx context.Context, checkpoint Checkpoint) error {
+	if checkpoint.UpdatedAt.IsZero() {
+		checkpoint.UpdatedAt = time.Now().UTC()
+	}
+	recent, err := json.Marshal(checkpoint.Recent)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// an update then an insert, upserts are written differently by every database
+	res, err := tx.ExecContext(ctx, s.query("UPDATE %s SET height = ?, hash = ?, updated_at = ?, recent = ? WHERE chain = ?"),
+		checkpoint.Height, checkpoint.Hash, checkpoint.UpdatedAt, string(recent), checkpoint.Chain)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// MySQL counts the changed rows only, the row may exist with the same values
+		var exists int
+		err = tx.QueryRowContext(ctx, s.query("SELECT 1 FROM %s WHERE chain = ?"), checkpoint.Chain).Scan(&exists)
+		if err == nil {
+			return tx.Commit()
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, s.query("INSERT INTO %s (chain, height, hash, updated_at, recent) VALUES (?, ?, ?, ?, ?)"),
+			checkpoint.Chain, checkpoint.Height, checkpoint.Hash, checkpoint.UpdatedAt, string(recent))
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// query sets the table of the statement and numbers its placeholders when needed
+func (s *SQLCheckpointStore) query(statement string) string {
+	statement = fmt.Sprintf(statement, s.table)
+	if !s.dollar {
+		return statement
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range statement {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,168 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FileCheckpointStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "checkpoints.json")
+	store := NewFileCheckpointStore(path)
+	ctx := context.Background()
+
+	checkpoint, err := store.Load(ctx, "polygon")
+	require.NoError(t, err)
+	assert.Nil(t, checkpoint)
+
+	require.NoError(t, store.Save(ctx, Checkpoint{Chain: "polygon", Height: 10, Hash: "0x10"}))
+	require.NoError(t, store.Save(ctx, Checkpoint{Chain: "ripple", Height: 7, Hash: "AB"}))
+	require.NoError(t, store.Save(ctx, Checkpoint{Chain: "polygon", Height: 11, Hash: "0x11"}))
+
+	// a new store reads the file
+	reopened := NewFileCheckpointStore(path)
+	checkpoint, err = reopened.Load(ctx, "polygon")
+	require.NoError(t, err)
+	require.NotNil(t, checkpoint)
+	assert.EqualValues(t, 11, checkpoint.Height)
+	assert.Equal(t, "0x11", checkpoint.Hash)
+	assert.False(t, checkpoint.UpdatedAt.IsZero())
+	checkpoint, err = reopened.Load(ctx, "ripple")
+	require.NoError(t, err)
+	assert.EqualValues(t, 7, checkpoint.Height)
+
+	// no temporary file is left
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+	_, err = NewFileCheckpointStore(path).Load(ctx, "polygon")
+	assert.Error(t, err)
+}
+
+func Test_SQLCheckpointStore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	store := NewSQLCheckpointStore(db).WithDollarPlaceholders()
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT height, hash, updated_at, recent FROM scanner_checkpoints WHERE chain = \$1`).
+		WithArgs("polygon").
+		WillReturnRows(sqlmock.NewRows([]string{"height", "hash", "updated_at", "recent"}))
+	checkpoint, err := store.Load(ctx, "polygon")
+	require.NoError(t, err)
+	assert.Nil(t, checkpoint)
+
+	// the first save inserts the row
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE scanner_checkpoints SET height = \$1, hash = \$2, updated_at = \$3, recent = \$4 WHERE chain = \$5`).
+		WithArgs(int64(10), "0x10", now, "null", "polygon").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT 1 FROM scanner_checkpoints WHERE chain = \$1`).
+		WithArgs("polygon").
+		WillReturnRows(sqlmock.NewRows([]string{"1"}))
+	mock.ExpectExec(`INSERT INTO scanner_checkpoints \(chain, height, hash, updated_at, recent\) VALUES \(\$1, \$2, \$3, \$4, \$5\)`).
+		WithArgs("polygon", int64(10), "0x10", now, "null").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	require.NoError(t, store.Save(ctx, Checkpoint{Chain: "polygon", Height: 10, Hash: "0x10", UpdatedAt: now}))
+
+	// the next ones update it
+	recent := []BlockRef{{Height: 10, Hash: "0x10"}}
+	recentJSON := `[{"height":10,"hash":"0x10"}]`
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE scanner_checkpoints SET`).
+		WithArgs(int64(11), "0x11", now, recentJSON, "polygon").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, store.Save(ctx, Checkpoint{Chain: "polygon", Height: 11, Hash: "0x11", UpdatedAt: now, Recent: recent}))
+
+	// MySQL reports no affected rows when the values are unchanged, the row is not inserted again
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE scanner_checkpoints SET`).
+		WithArgs(int64(11), "0x11", now, recentJSON, "polygon").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT 1 FROM scanner_checkpoints WHERE chain = \$1`).
+		WithArgs("polygon").
+		WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	mock.ExpectCommit()
+	require.NoError(t, store.Save(ctx, Checkpoint{Chain: "polygon", Height: 11, Hash: "0x11", UpdatedAt: now, Recent: recent}))
+
+	mock.ExpectQuery(`SELECT height, hash, updated_at, recent FROM scanner_checkpoints`).
+		WithArgs("polygon").
+		WillReturnRows(sqlmock.NewRows([]string{"height", "hash", "updated_at", "recent"}).AddRow(11, "0x11", now, recentJSON))
+	checkpoint, err = store.Load(ctx, "polygon")
+	require.NoError(t, err)
+	assert.Equal(t, &Checkpoint{Chain: "polygon", Height: 11, Hash: "0x11", UpdatedAt: now, Recent: recent}, checkpoint)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_SQLCheckpointStore_Placeholders(t *testing.T) {
+	store := NewSQLCheckpointStore(nil).WithTable("checkpoints")
+	assert.Equal(t, "SELECT hash FROM checkpoints WHERE chain = ?", store.query("SELECT hash FROM %s WHERE chain = ?"))
+	store.WithDollarPlaceholders()
+	assert.Equal(t, "UPDATE checkpoints SET height = $1 WHERE chain = $2", store.query("UPDATE %s SET height = ? WHERE chain = ?"))
+}
+
+// failingStore fails to load
+type failingStore struct{}
+
+func (failingStore) Load(ctx context.Context, chain string) (*Checkpoint, error) {
+	return nil, errors.New("disk failure")
+}
+
+func (failingStore) Save(ctx context.Context, checkpoint Checkpoint) error {
+	return errors.New("disk failure")
+}
+
+func Test_Scanner_ResumesFromCheckpoint(t *testing.T) {
+	source := newFakeSource(5)
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoints.json"))
+	ctx := context.Background()
+	require.NoError(t, store.Save(ctx, Checkpoint{Chain: "fake", Height: 2, Hash: blockHash(2, "")}))
+
+	c := &collector{}
+	s := NewScanner("fake", source).
+		WithLogger(hclog.NewNullLogger()).
+		WithInterval(5 * time.Millisecond).
+		WithCheckpoints(store).
+		OnBlock(c.onBlock)
+	stop := runScanner(t, s)
+	require.Eventually(t, func() bool { return s.Height() == 5 }, time.Second, 5*time.Millisecond)
+	stop()
+	assert.Equal(t, []int64{3, 4, 5}, c.heights())
+
+	checkpoint, err := store.Load(ctx, "fake")
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, checkpoint.Height)
+	assert.Equal(t, blockHash(5, ""), checkpoint.Hash)
+
+	// the start height overrides the checkpoint
+	c = &collector{}
+	s = NewScanner("fake", source).
+		WithLogger(hclog.NewNullLogger()).
+		WithInterval(5 * time.Millisecond).
+		WithCheckpoints(store).
+		WithStartHeight(4).
+		OnBlock(c.onBlock)
+	stop = runScanner(t, s)
+	require.Eventually(t, func() bool { return s.Height() == 5 }, time.Second, 5*time.Millisecond)
+	stop()
+	assert.Equal(t, []int64{4, 5}, c.heights())
+
+	// the scan does not start from the head when the checkpoint can not be read
+	err = NewScanner("fake", source).WithLogger(hclog.NewNullLogger()).WithCheckpoints(failingStore{}).Run(ctx)
+	assert.ErrorContains(t, err, "disk failure")
+}
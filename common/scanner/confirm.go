@@ -0,0 +1,116 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+var (
+	// ErrNoFinality is returned by Run with WithFinality when the BlockSource is not a FinalitySource
+	ErrNoFinality = errors.New("block source has no finality")
+)
+
+// WithConfirmations sets the number of confirmations of a confirmed block, the head has 1 confirmation.
+// Default is 1: the blocks are confirmed as soon as they are seen.
+func (s *Scanner) WithConfirmations(depth int64) *Scanner {
+	if depth > 0 {
+		s.confirmations = depth
+	}
+	return s
+}
+
+// WithFinality confirms the blocks once the chain tells they are final, the BlockSource must be a
+// FinalitySource. It replaces the confirmations.
+func (s *Scanner) WithFinality() *Scanner {
+	s.finality = true
+	return s
+}
+
+// OnConfirmedBlock adds a handler of the confirmed blocks, called in order of height after the block is seen
+// and once it has enough confirmations or is final
+func (s *Scanner) OnConfirmedBlock(handler BlockHandler) *Scanner {
+	s.confirmedBlockHandlers = append(s.confirmedBlockHandlers, handler)
+	return s
+}
+
+// OnConfirmedTransaction adds a handler of the transactions of the confirmed blocks, e.g. to credit deposits
+func (s *Scanner) OnConfirmedTransaction(handler TxHandler) *Scanner {
+	s.confirmedTxHandlers = append(s.confirmedTxHandlers, handler)
+	return s
+}
+
+// ConfirmedHeight returns the height of the last confirmed block
+func (s *Scanner) ConfirmedHeight() int64 {
+	return atomic.LoadInt64(&s.confirmed)
+}
+
+// gating reports whether the confirmed blocks are handled. The checkpoint is then the last confirmed
+// block, so that no block is left unconfirmed after a restart.
+func (s *Scanner) gating() bool {
+	return len(s.confirmedBlockHandlers) > 0 || len(s.confirmedTxHandlers) > 0
+}
+
+// confirmedTip returns the height of the highest confirmed block, given the head of the chain
+func (s *Scanner) confirmedTip(ctx context.Context, head int64) (int64, error) {
+	if !s.finality {
+		return head - s.confirmations + 1, nil
+	}
+	source, ok := s.source.(FinalitySource)
+	if !ok {
+		return 0, ErrNoFinality
+	}
+	return source.FinalizedHeight(ctx)
+}
+
+// confirm handles the seen blocks which are confirmed given the head of the chain
+func (s *Scanner) confirm(ctx context.Context, head int64) {
+	if !s.gating() {
+		return
+	}
+	tip, err := s.confirmedTip(ctx, head)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.log.Warn("getting confirmed height failed", "err", err)
+		}
+		return
+	}
+	if seen := s.Height(); tip > seen {
+		tip = seen
+	}
+
+	for height := s.ConfirmedHeight() + 1; height <= tip && ctx.Err() == nil; height++ {
+		block, ok := s.recent[height]
+		if !ok {
+			// out of the window of recent blocks, the canonical block is confirmed
+			if block, err = s.source.BlockByHeight(ctx, height); err != nil {
+				s.log.Warn("getting confirmed block failed", "height", height, "err", err)
+				return
+			}
+		}
+		if err = s.handleConfirmed(ctx, block); err != nil {
+			s.log.Warn("handling confirmed block failed", "height", height, "hash", block.Hash, "err", err)
+			return
+		}
+		atomic.StoreInt64(&s.confirmed, height)
+		s.saveCheckpoint(ctx, block)
+		s.log.Debug("confirmed block", "height", height, "hash", block.Hash)
+	}
+}
+
+func (s *Scanner) handleConfirmed(ctx context.Context, block *Block) error {
+	for _, h := range s.confirmedBlockHandlers {
+		if err := h(ctx, block); err != nil {
+			return err
+		}
+	}
+	for _, tx := range block.Transactions {
+		for _, h := range s.confirmedTxHandlers {
+			if err := h(ctx, block, tx); err != nil {
+				return fmt.Errorf("transaction %s: %w", tx.Hash, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package scanner
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// finalSource is a fakeSource which tells its finalized height
+type finalSource struct {
+	*fakeSource
+	lock      sync.Mutex
+	finalized int64
+}
+
+func (s *finalSource) finalize(height int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.finalized = height
+}
+
+func (s *finalSource) FinalizedHeight(ctx context.Context) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.finalized, nil
+}
+
+func newConfirmScanner(source BlockSource, e *events) *Scanner {
+	return newReorgScanner(source, e).
+		WithStartHeight(1).
+		OnConfirmedBlock(func(ctx context.Context, block *Block) error {
+			e.add("confirmed " + block.Hash)
+			return nil
+		})
+}
+
+func Test_Scanner_Confirmations(t *testing.T) {
+	source := newFakeSource(5)
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoints.json"))
+	e := &events{}
+	var txs []string
+	s := newConfirmScanner(source, e).
+		WithConfirmations(3).
+		WithCheckpoints(store).
+		OnConfirmedTransaction(func(ctx context.Context, block *Block, tx *Transaction) error {
+			txs = append(txs, tx.Hash)
+			return nil
+		})
+	stop := runScanner(t, s)
+
+	// the head has 1 confirmation, block 3 has 3
+	require.Eventually(t, func() bool { return s.ConfirmedHeight() == 3 }, time.Second, 5*time.Millisecond)
+	assert.EqualValues(t, 5, s.Height())
+	assert.Equal(t, []string{
+		"block 0x1", "block 0x2", "block 0x3", "block 0x4", "block 0x5",
+		"confirmed 0x1", "confirmed 0x2", "confirmed 0x3",
+	}, e.get())
+
+	source.mine(1)
+	require.Eventually(t, func() bool { return s.ConfirmedHeight() == 4 }, time.Second, 5*time.Millisecond)
+	stop()
+	assert.Equal(t, []string{"block 0x6", "confirmed 0x4"}, e.get()[8:])
+	assert.Equal(t, []string{"0x1-tx0", "0x1-tx1", "0x2-tx0", "0x2-tx1", "0x3-tx0", "0x3-tx1", "0x4-tx0", "0x4-tx1"}, txs)
+
+	// the checkpoint is the last confirmed block, the unconfirmed blocks are seen again after a restart
+	checkpoint, err := store.Load(context.Background(), "fake")
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, checkpoint.Height)
+}
+
+func Test_Scanner_Finality(t *testing.T) {
+	e := &events{}
+	err := newConfirmScanner(newFakeSource(3), e).WithFinality().Run(context.Background())
+	assert.ErrorIs(t, err, ErrNoFinality)
+
+	source := &finalSource{fakeSource: newFakeSource(5), finalized: 2}
+	s := newConfirmScanner(source, e).WithFinality()
+	stop := runScanner(t, s)
+	defer stop()
+
+	require.Eventually(t, func() bool { return s.ConfirmedHeight() == 2 }, time.Second, 5*time.Millisecond)
+	source.finalize(4)
+	require.Eventually(t, func() bool { return s.ConfirmedHeight() == 4 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{
+		"block 0x1", "block 0x2", "block 0x3", "block 0x4", "block 0x5",
+		"confirmed 0x1", "confirmed 0x2", "confirmed 0x3", "confirmed 0x4",
+	}, e.get())
+}
+
+func Test_Scanner_ReorgOfUnconfirmedBlocks(t *testing.T) {
+	source := newFakeSource(3)
+	e := &events{}
+	s := newConfirmScanner(source, e).WithConfirmations(2)
+	stop := runScanner(t, s)
+	defer stop()
+	require.Eventually(t, func() bool { return s.ConfirmedHeight() == 2 }, time.Second, 5*time.Millisecond)
+
+	// the orphaned block 3 is never confirmed
+	source.reorg(3, "b")
+	source.mine(1)
+	require.Eventually(t, func() bool { return s.ConfirmedHeight() == 3 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{
+		"block 0x1", "block 0x2", "block 0x3", "confirmed 0x1", "confirmed 0x2",
+		"rollback 0x3", "block 0x3b", "block 0x4", "confirmed 0x3b",
+	}, e.get())
+}
+
+func Test_Scanner_ReorgOfConfirmedBlocks(t *testing.T) {
+	source := newFakeSource(3)
+	e := &events{}
+	s := newConfirmScanner(source, e)
+	stop := runScanner(t, s)
+	defer stop()
+	require.Eventually(t, func() bool { return s.ConfirmedHeight() == 3 }, time.Second, 5*time.Millisecond)
+
+	// the depth is too low for the reorg, the canonical blocks are confirmed again
+	source.reorg(2, "b")
+	source.mine(1)
+	require.Eventually(t, func() bool { return s.ConfirmedHeight() == 4 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{
+		"block 0x1", "block 0x2", "block 0x3", "confirmed 0x1", "confirmed 0x2", "confirmed 0x3",
+		"rollback 0x3", "rollback 0x2", "block 0x2b", "block 0x3b", "block 0x4", "confirmed 0x2b", "confirmed 0x3b", "confirmed 0x4",
+	}, e.get())
+}
@@ -0,0 +1,98 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// DefaultReorgWindow is the number of recent blocks kept to find the common ancestor of a reorg
+const DefaultReorgWindow = 128
+
+var (
+	// ErrReorgTooDeep is returned by Run when the common ancestor of a reorg is older than the window
+	// of recent blocks, the orphaned blocks can not all be rolled back
+	ErrReorgTooDeep = errors.New("reorg deeper than the window of recent blocks")
+)
+
+// RollbackHandler is called for every block orphaned by a reorg, from the highest to the lowest,
+// before the blocks of the canonical chain are handled again. The blocks processed before a restart only
+// carry their height and hash.
+type RollbackHandler func(ctx context.Context, block *Block) error
+
+// WithReorgWindow sets the number of recent blocks kept to detect reorgs and find their common ancestor
+func (s *Scanner) WithReorgWindow(size int) *Scanner {
+	if size > 0 {
+		s.window = size
+	}
+	return s
+}
+
+// OnRollback adds a handler of the blocks orphaned by reorgs, e.g. to cancel their credited deposits
+func (s *Scanner) OnRollback(handler RollbackHandler) *Scanner {
+	s.rollbackHandlers = append(s.rollbackHandlers, handler)
+	return s
+}
+
+// remember adds the processed block to the window of recent blocks
+func (s *Scanner) remember(block *Block) {
+	if s.recent == nil {
+		s.recent = make(map[int64]*Block, s.window)
+	}
+	s.recent[block.Height] = block
+	delete(s.recent, block.Height-int64(s.window))
+}
+
+// isOrphaning reports whether the block does not extend the processed chain
+func (s *Scanner) isOrphaning(block *Block) bool {
+	parent, ok := s.recent[block.Height-1]
+	return ok && block.ParentHash != "" && block.ParentHash != parent.Hash
+}
+
+// rollback walks back from the height to the common ancestor of the processed chain and of the canonical one,
+// and rolls back the orphaned blocks. It returns the height of the common ancestor.
+// The window is only changed once all the rollback handlers succeed, a failed rollback is detected again.
+func (s *Scanner) rollback(ctx context.Context, height int64) (int64, error) {
+	var orphans []*Block
+	var ancestor *Block
+	for h := height; ancestor == nil; h-- {
+		seen, ok := s.recent[h]
+		if !ok {
+			return 0, fmt.Errorf("%w: no common ancestor in the %d blocks below %d", ErrReorgTooDeep, len(orphans), height+1)
+		}
+		canonical, err := s.source.BlockByHeight(ctx, h)
+		if err != nil {
+			return 0, fmt.Errorf("getting canonical block %d: %w", h, err)
+		}
+		if canonical.Hash == seen.Hash {
+			ancestor = seen
+		} else {
+			orphans = append(orphans, seen)
+		}
+	}
+
+	s.log.Warn("reorg detected", "ancestor", ancestor.Height, "hash", ancestor.Hash, "depth", len(orphans))
+	for _, orphan := range orphans {
+		for _, h := range s.rollbackHandlers {
+			if err := h(ctx, orphan); err != nil {
+				return 0, fmt.Errorf("rolling back block %d: %w", orphan.Height, err)
+			}
+		}
+		s.log.Info("rolled back block", "height", orphan.Height, "hash", orphan.Hash)
+	}
+
+	for _, orphan := range orphans {
+		delete(s.recent, orphan.Height)
+	}
+	atomic.StoreInt64(&s.height, ancestor.Height)
+	if confirmed := s.ConfirmedHeight(); s.gating() && ancestor.Height < confirmed {
+		// the confirmation depth is too low for the chain, the confirmed handlers see the blocks again
+		s.log.Error("reorg orphaned confirmed blocks", "ancestor", ancestor.Height, "confirmed", confirmed)
+		atomic.StoreInt64(&s.confirmed, ancestor.Height)
+		s.saveCheckpoint(ctx, ancestor)
+	} else if !s.gating() {
+		s.saveCheckpoint(ctx, ancestor)
+	}
+	return ancestor.Height, nil
+}
@@ -0,0 +1,147 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// events records the handled and rolled back blocks in order
+type events struct {
+	lock sync.Mutex
+	list []string
+}
+
+func (e *events) add(event string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.list = append(e.list, event)
+}
+
+func (e *events) get() []string {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return append([]string(nil), e.list...)
+}
+
+func newReorgScanner(source BlockSource, e *events) *Scanner {
+	return NewScanner("fake", source).
+		WithLogger(hclog.NewNullLogger()).
+		WithInterval(5 * time.Millisecond).
+		OnBlock(func(ctx context.Context, block *Block) error {
+			e.add("block " + block.Hash)
+			return nil
+		}).
+		OnRollback(func(ctx context.Context, block *Block) error {
+			e.add("rollback " + block.Hash)
+			return nil
+		})
+}
+
+func Test_Scanner_Reorg(t *testing.T) {
+	source := newFakeSource(3)
+	e := &events{}
+	s := newReorgScanner(source, e).WithStartHeight(1)
+	stop := runScanner(t, s)
+	defer stop()
+	require.Eventually(t, func() bool { return s.Height() == 3 }, time.Second, 5*time.Millisecond)
+
+	// blocks 2 and 3 are replaced, the reorg is seen with block 4
+	source.reorg(2, "b")
+	source.mine(1)
+	require.Eventually(t, func() bool { return len(e.get()) == 8 }, time.Second, 5*time.Millisecond)
+
+	assert.Equal(t, []string{
+		"block 0x1", "block 0x2", "block 0x3",
+		"rollback 0x3", "rollback 0x2",
+		"block 0x2b", "block 0x3b", "block 0x4",
+	}, e.get())
+	assert.EqualValues(t, 4, s.Height())
+}
+
+func Test_Scanner_ReorgRollbackFails(t *testing.T) {
+	source := newFakeSource(3)
+	e := &events{}
+	failures := 1
+	s := newReorgScanner(source, e).
+		WithStartHeight(1).
+		OnRollback(func(ctx context.Context, block *Block) error {
+			if failures > 0 {
+				failures--
+				return errors.New("database down")
+			}
+			return nil
+		})
+	stop := runScanner(t, s)
+	defer stop()
+	require.Eventually(t, func() bool { return s.Height() == 3 }, time.Second, 5*time.Millisecond)
+
+	source.reorg(3, "b")
+	source.mine(1)
+	require.Eventually(t, func() bool { return s.Height() == 4 }, time.Second, 5*time.Millisecond)
+
+	// the rollback is detected again after the failure
+	assert.Equal(t, []string{
+		"block 0x1", "block 0x2", "block 0x3",
+		"rollback 0x3", "rollback 0x3",
+		"block 0x3b", "block 0x4",
+	}, e.get())
+}
+
+func Test_Scanner_ReorgTooDeep(t *testing.T) {
+	source := newFakeSource(5)
+	e := &events{}
+	s := newReorgScanner(source, e).WithStartHeight(1).WithReorgWindow(2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	require.Eventually(t, func() bool { return s.Height() == 5 }, time.Second, 5*time.Millisecond)
+
+	source.reorg(2, "b")
+	source.mine(1)
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrReorgTooDeep)
+	case <-time.After(time.Second):
+		t.Fatal("the scanner did not stop")
+	}
+	assert.NotContains(t, e.get(), "rollback 0x5")
+}
+
+func Test_Scanner_ReorgOfCheckpoint(t *testing.T) {
+	source := newFakeSource(3)
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoints.json"))
+	e := &events{}
+	s := newReorgScanner(source, e).WithStartHeight(1).WithCheckpoints(store)
+	stop := runScanner(t, s)
+	require.Eventually(t, func() bool { return s.Height() == 3 }, time.Second, 5*time.Millisecond)
+	stop()
+
+	// blocks 2 and 3 are replaced while the scanner is stopped
+	source.reorg(2, "b")
+	source.mine(1)
+
+	s = newReorgScanner(source, e).WithCheckpoints(store)
+	stop = runScanner(t, s)
+	defer stop()
+	require.Eventually(t, func() bool { return s.Height() == 4 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{
+		"block 0x1", "block 0x2", "block 0x3",
+		"rollback 0x3", "rollback 0x2",
+		"block 0x2b", "block 0x3b", "block 0x4",
+	}, e.get())
+
+	checkpoint, err := store.Load(context.Background(), "fake")
+	require.NoError(t, err)
+	assert.Equal(t, blockHash(4, ""), checkpoint.Hash)
+	assert.Equal(t, []BlockRef{{1, blockHash(1, "")}, {2, blockHash(2, "b")}, {3, blockHash(3, "b")}}, checkpoint.Recent)
+}
@@ -0,0 +1,277 @@
+// Package scanner follows the blocks of a chain and passes them to handlers. The chains plug in
+// through a BlockSource:
+//
+//	s := scanner.NewScanner("polygon", client.NewBlockSource(ethClient)).
+//		WithInterval(2 * time.Second).
+//		OnTransaction(func(ctx context.Context, block *scanner.Block, tx *scanner.Transaction) error {
+//			return deposits.Check(block.Height, tx.Hash)
+//		})
+//	err := s.Run(ctx)
+//
+// The blocks are passed to the OnBlock and OnTransaction handlers as soon as they are seen, and to the
+// OnConfirmedBlock and OnConfirmedTransaction handlers once they have WithConfirmations confirmations, or
+// once they are final WithFinality.
+//
+// Backfill passes the blocks of a past range to the same handlers, e.g. after adding a watched address.
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultInterval is the interval between two polls of the chain's head
+const DefaultInterval = time.Second
+
+// BlockHandler is called once per scanned block, before the TxHandlers of its transactions
+type BlockHandler func(ctx context.Context, block *Block) error
+
+// TxHandler is called once per transaction of a scanned block
+type TxHandler func(ctx context.Context, block *Block, tx *Transaction) error
+
+// Scanner polls a BlockSource and passes the blocks to its handlers in order of height. A block whose
+// fetch or handlers fail is retried at the next poll, so the handlers must be idempotent.
+//
+// A block whose parent is not the processed block below it reveals a reorg: the scanner walks back to the
+// common ancestor, rolls back the orphaned blocks and handles the blocks of the canonical chain again.
+type Scanner struct {
+	name   string
+	source BlockSource
+	log    hclog.Logger
+
+	interval    time.Duration
+	startHeight int64 // 0 resumes from the checkpoint, or starts after the head
+	checkpoints CheckpointStore
+
+	blockHandlers    []BlockHandler
+	txHandlers       []TxHandler
+	rollbackHandlers []RollbackHandler
+
+	confirmations          int64 // confirmations of a confirmed block, the head has 1
+	finality               bool  // confirm the final blocks of a FinalitySource instead
+	confirmedBlockHandlers []BlockHandler
+	confirmedTxHandlers    []TxHandler
+
+	workers          int // concurrent fetches of Backfill
+	batchSize        int // blocks fetched at once by a Backfill worker
+	progressInterval time.Duration
+	progressHandlers []ProgressHandler
+
+	window int              // number of recent blocks kept
+	recent map[int64]*Block // the processed blocks of the window by height
+
+	height    int64 // last scanned height, read by Height while running
+	confirmed int64 // last confirmed height, read by ConfirmedHeight while running
+	head      int64 // height of the chain's head at the last poll
+}
+
+// NewScanner creates a scanner of the chain's blocks
+func NewScanner(name string, source BlockSource) *Scanner {
+	return &Scanner{
+		name:     name,
+		source:   source,
+		log:      hclog.L().Named("scanner." + name),
+		interval: DefaultInterval,
+		window:   DefaultReorgWindow,
+
+		confirmations: 1,
+
+		workers:          DefaultWorkers,
+		batchSize:        1,
+		progressInterval: DefaultProgressInterval,
+	}
+}
+
+// WithLogger sets the logger of the scanner
+func (s *Scanner) WithLogger(logger hclog.Logger) *Scanner {
+	s.log = logger
+	return s
+}
+
+// WithInterval sets the interval between two polls of the chain's head
+func (s *Scanner) WithInterval(interval time.Duration) *Scanner {
+	if interval > 0 {
+		s.interval = interval
+	}
+	return s
+}
+
+// WithStartHeight sets the first scanned height, overriding the checkpoint. By default the scan resumes
+// after the checkpoint, or starts with the block after the head.
+func (s *Scanner) WithStartHeight(height int64) *Scanner {
+	s.startHeight = height
+	return s
+}
+
+// WithCheckpoints saves the checkpoint of every processed block into the store, and resumes from it on Run.
+// With confirmed handlers the checkpoint is the last confirmed block.
+func (s *Scanner) WithCheckpoints(store CheckpointStore) *Scanner {
+	s.checkpoints = store
+	return s
+}
+
+// OnBlock adds a handler of the scanned blocks
+func (s *Scanner) OnBlock(handler BlockHandler) *Scanner {
+	s.blockHandlers = append(s.blockHandlers, handler)
+	return s
+}
+
+// OnTransaction adds a handler of the transactions of the scanned blocks
+func (s *Scanner) OnTransaction(handler TxHandler) *Scanner {
+	s.txHandlers = append(s.txHandlers, handler)
+	return s
+}
+
+// Height returns the height of the last scanned block
+func (s *Scanner) Height() int64 {
+	return atomic.LoadInt64(&s.height)
+}
+
+// Run scans the blocks until ctx is done. It returns ctx's error, the error of getting the start height,
+// ErrNoFinality or ErrReorgTooDeep.
+func (s *Scanner) Run(ctx context.Context) error {
+	if _, ok := s.source.(FinalitySource); s.finality && !ok {
+		return fmt.Errorf("%s: %w", s.name, ErrNoFinality)
+	}
+	next, err := s.start(ctx)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&s.height, next-1)
+	atomic.StoreInt64(&s.confirmed, next-1)
+	s.log.Info("scanning", "from", next, "interval", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if next, err = s.poll(ctx, next); err != nil {
+			return err
+		}
+		s.confirm(ctx, s.head)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// start returns the first height to scan
+func (s *Scanner) start(ctx context.Context) (int64, error) {
+	if s.startHeight > 0 {
+		return s.startHeight, nil
+	}
+	if s.checkpoints != nil {
+		checkpoint, err := s.checkpoints.Load(ctx, s.name)
+		if err != nil {
+			// starting from the head would skip the blocks since the checkpoint
+			return 0, fmt.Errorf("loading checkpoint of %s: %w", s.name, err)
+		}
+		if checkpoint != nil {
+			s.log.Info("resuming from checkpoint", "height", checkpoint.Height, "hash", checkpoint.Hash)
+			// the window is restored, a reorg of the checkpoint block while stopped is rolled back down to
+			// the common ancestor like any other
+			for _, ref := range checkpoint.Recent {
+				s.remember(&Block{Height: ref.Height, Hash: ref.Hash})
+			}
+			s.remember(&Block{Height: checkpoint.Height, Hash: checkpoint.Hash})
+			return checkpoint.Height + 1, nil
+		}
+	}
+	head, err := s.source.LatestHeight(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("getting latest height of %s: %w", s.name, err)
+	}
+	return head + 1, nil
+}
+
+// poll scans the blocks from next up to the head, it returns the next height to scan.
+// The errors are logged and retried at the next poll, except ErrReorgTooDeep.
+func (s *Scanner) poll(ctx context.Context, next int64) (int64, error) {
+	head, err := s.source.LatestHeight(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.log.Warn("getting latest height failed", "err", err)
+		}
+		return next, nil
+	}
+	s.head = head
+
+	for next <= head && ctx.Err() == nil {
+		block, err := s.source.BlockByHeight(ctx, next)
+		if errors.Is(err, ErrBlockNotFound) {
+			// the head is not served by every node of the provider yet
+			s.log.Debug("block not found", "height", next)
+			return next, nil
+		}
+		if err != nil {
+			s.log.Warn("getting block failed", "height", next, "err", err)
+			return next, nil
+		}
+
+		if s.isOrphaning(block) {
+			ancestor, err := s.rollback(ctx, next-1)
+			if errors.Is(err, ErrReorgTooDeep) {
+				return next, err
+			}
+			if err != nil {
+				s.log.Warn("rolling back reorg failed", "height", next, "err", err)
+				return next, nil
+			}
+			next = ancestor + 1
+			continue
+		}
+
+		if err = s.handle(ctx, block); err != nil {
+			s.log.Warn("handling block failed", "height", next, "hash", block.Hash, "err", err)
+			return next, nil
+		}
+		s.remember(block)
+		atomic.StoreInt64(&s.height, next)
+		if !s.gating() {
+			s.saveCheckpoint(ctx, block)
+		}
+		s.log.Debug("scanned block", "height", next, "hash", block.Hash, "txs", len(block.Transactions))
+		next++
+	}
+	return next, nil
+}
+
+// saveCheckpoint records the processed block, a failure only means that the blocks since the previous
+// checkpoint are processed again after a restart
+func (s *Scanner) saveCheckpoint(ctx context.Context, block *Block) {
+	if s.checkpoints == nil {
+		return
+	}
+	checkpoint := Checkpoint{Chain: s.name, Height: block.Height, Hash: block.Hash}
+	for height := block.Height - int64(s.window) + 1; height < block.Height; height++ {
+		if seen, ok := s.recent[height]; ok {
+			checkpoint.Recent = append(checkpoint.Recent, BlockRef{Height: seen.Height, Hash: seen.Hash})
+		}
+	}
+	err := s.checkpoints.Save(ctx, checkpoint)
+	if err != nil {
+		s.log.Error("saving checkpoint failed", "height", block.Height, "err", err)
+	}
+}
+
+func (s *Scanner) handle(ctx context.Context, block *Block) error {
+	for _, h := range s.blockHandlers {
+		if err := h(ctx, block); err != nil {
+			return err
+		}
+	}
+	for _, tx := range block.Transactions {
+		for _, h := range s.txHandlers {
+			if err := h(ctx, block, tx); err != nil {
+				return fmt.Errorf("transaction %s: %w", tx.Hash, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource is a chain of blocks with two transactions each, the blocks are added by mine
+type fakeSource struct {
+	lock    sync.Mutex
+	blocks  []*Block // blocks[i] has height i
+	failing map[int64]int
+}
+
+func newFakeSource(height int64) *fakeSource {
+	s := &fakeSource{failing: make(map[int64]int)}
+	s.blocks = append(s.blocks, &Block{Hash: blockHash(0, "")})
+	s.mine(height)
+	return s
+}
+
+func blockHash(height int64, fork string) string {
+	return fmt.Sprintf("0x%d%s", height, fork)
+}
+
+// mine appends n blocks on top of the chain
+func (s *fakeSource) mine(n int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i := int64(0); i < n; i++ {
+		parent := s.blocks[len(s.blocks)-1]
+		height := int64(len(s.blocks))
+		hash := blockHash(height, "")
+		s.blocks = append(s.blocks, &Block{
+			Height:     height,
+			Hash:       hash,
+			ParentHash: parent.Hash,
+			Transactions: []*Transaction{
+				{Hash: hash + "-tx0"},
+				{Hash: hash + "-tx1"},
+			},
+		})
+	}
+}
+
+// reorg replaces the blocks from the height up to the head with the blocks of a fork
+func (s *fakeSource) reorg(height int64, fork string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for h := height; h < int64(len(s.blocks)); h++ {
+		hash := blockHash(h, fork)
+		s.blocks[h] = &Block{
+			Height:       h,
+			Hash:         hash,
+			ParentHash:   s.blocks[h-1].Hash,
+			Transactions: []*Transaction{{Hash: hash + "-tx0"}},
+		}
+	}
+}
+
+// fail makes the next n fetches of the block fail
+func (s *fakeSource) fail(height int64, n int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failing[height] = n
+}
+
+func (s *fakeSource) LatestHeight(ctx context.Context) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return int64(len(s.blocks) - 1), nil
+}
+
+func (s *fakeSource) BlockByHeight(ctx context.Context, height int64) (*Block, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.failing[height] > 0 {
+		s.failing[height]--
+		return nil, errors.New("node unavailable")
+	}
+	if height >= int64(len(s.blocks)) {
+		return nil, ErrBlockNotFound
+	}
+	return s.blocks[height], nil
+}
+
+// collector records the handled blocks and transactions
+type collector struct {
+	lock   sync.Mutex
+	blocks []int64
+	txs    []string
+}
+
+func (c *collector) onBlock(ctx context.Context, block *Block) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.blocks = append(c.blocks, block.Height)
+	return nil
+}
+
+func (c *collector) onTx(ctx context.Context, block *Block, tx *Transaction) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.txs = append(c.txs, tx.Hash)
+	return nil
+}
+
+func (c *collector) heights() []int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]int64(nil), c.blocks...)
+}
+
+func runScanner(t *testing.T, s *Scanner) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	return func() {
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	}
+}
+
+func Test_Scanner_FollowsHead(t *testing.T) {
+	source := newFakeSource(5)
+	c := &collector{}
+	s := NewScanner("fake", source).
+		WithLogger(hclog.NewNullLogger()).
+		WithInterval(5 * time.Millisecond).
+		OnBlock(c.onBlock).
+		OnTransaction(c.onTx)
+	stop := runScanner(t, s)
+	defer stop()
+
+	// the scan starts after the head
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, c.heights())
+
+	source.mine(3)
+	require.Eventually(t, func() bool { return s.Height() == 8 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []int64{6, 7, 8}, c.heights())
+	assert.Equal(t, []string{"0x6-tx0", "0x6-tx1", "0x7-tx0", "0x7-tx1", "0x8-tx0", "0x8-tx1"}, c.txs)
+}
+
+func Test_Scanner_StartHeight(t *testing.T) {
+	source := newFakeSource(5)
+	c := &collector{}
+	s := NewScanner("fake", source).
+		WithLogger(hclog.NewNullLogger()).
+		WithInterval(5 * time.Millisecond).
+		WithStartHeight(2).
+		OnBlock(c.onBlock)
+	stop := runScanner(t, s)
+	defer stop()
+
+	require.Eventually(t, func() bool { return s.Height() == 5 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []int64{2, 3, 4, 5}, c.heights())
+}
+
+func Test_Scanner_RetriesFailedBlocks(t *testing.T) {
+	source := newFakeSource(3)
+	source.fail(2, 2)
+
+	c := &collector{}
+	handlerFailures := 1
+	s := NewScanner("fake", source).
+		WithLogger(hclog.NewNullLogger()).
+		WithInterval(5 * time.Millisecond).
+		WithStartHeight(1).
+		OnBlock(c.onBlock).
+		OnTransaction(func(ctx context.Context, block *Block, tx *Transaction) error {
+			if block.Height == 3 && handlerFailures > 0 {
+				handlerFailures--
+				return errors.New("database down")
+			}
+			return nil
+		})
+	stop := runScanner(t, s)
+	defer stop()
+
+	require.Eventually(t, func() bool { return s.Height() == 3 }, time.Second, 5*time.Millisecond)
+	// block 3 is handled again after its transaction handler failed
+	assert.Equal(t, []int64{1, 2, 3, 3}, c.heights())
+}
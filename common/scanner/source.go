@@ -0,0 +1,55 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrBlockNotFound is returned by a BlockSource for a height which is not produced yet
+	ErrBlockNotFound = errors.New("block not found")
+)
+
+// Block is a block of any chain, as needed by the handlers
+type Block struct {
+	Height       int64
+	Hash         string
+	ParentHash   string
+	Time         time.Time
+	Transactions []*Transaction
+
+	// Raw is the block of the chain's client, e.g. *ethereum.Block or *ripple.LedgerResp
+	Raw interface{}
+}
+
+// Transaction is a transaction of a Block
+type Transaction struct {
+	Hash string
+
+	// Raw is the transaction of the chain's client, e.g. ethereum.Transaction or *ripple.TxResp
+	Raw interface{}
+}
+
+// BlockSource reads the blocks of a chain. Supporting a new chain means implementing a BlockSource
+// over its client, e.g. client.NewBlockSource for the EVM chains and ripple.NewBlockSource.
+type BlockSource interface {
+	// LatestHeight returns the height of the chain's head
+	LatestHeight(ctx context.Context) (int64, error)
+	// BlockByHeight returns the block with its transactions, ErrBlockNotFound when it is not produced yet
+	BlockByHeight(ctx context.Context, height int64) (*Block, error)
+}
+
+// FinalitySource is implemented by the BlockSources of chains which tell the blocks which are final,
+// e.g. the finalized block of Ethereum or the validated ledgers of XRP
+type FinalitySource interface {
+	// FinalizedHeight returns the height of the latest final block
+	FinalizedHeight(ctx context.Context) (int64, error)
+}
+
+// BatchSource is implemented by the BlockSources which fetch several blocks in one request, e.g. with
+// JSON-RPC batches. Backfill uses it WithBatchSize.
+type BatchSource interface {
+	// BlocksByHeight returns the blocks in the order of heights, ErrBlockNotFound when one is not produced yet
+	BlocksByHeight(ctx context.Context, heights []int64) ([]*Block, error)
+}
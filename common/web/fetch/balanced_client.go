@@ -0,0 +1,529 @@
+package fetch
+
+import (
+	"context"
+	"crypto-trade-client/common/config"
+	"crypto-trade-client/common/web"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxFailures is the number of consecutive failures after which an endpoint is taken out of rotation
+	DefaultMaxFailures = 2
+	// DefaultCooldown is how long a failing endpoint stays out of rotation, unless a health check passes before
+	DefaultCooldown = 30 * time.Second
+	// DefaultHealthCheckInterval is the interval of the health checks started by BalancedClient.Start
+	DefaultHealthCheckInterval = 15 * time.Second
+	// DefaultMaxLag is the number of blocks an endpoint may be behind the highest endpoint before it is avoided
+	DefaultMaxLag = 5
+)
+
+var (
+	// ErrNoEndpoints is returned when a BalancedClient has no endpoint
+	ErrNoEndpoints = errors.New("no endpoints")
+)
+
+// HealthCheck checks the endpoint of the client, it returns the endpoint's chain height or 0 when it does
+// not track heights. Endpoints more than the max lag behind the highest one are avoided.
+type HealthCheck func(ctx context.Context, client *Client) (height uint64, err error)
+
+type endpointState struct {
+	url    string
+	weight int
+	client *Client
+
+	failures  int
+	downUntil time.Time
+	height    uint64
+	lagging   bool
+}
+
+func (ep *endpointState) available(now time.Time) bool {
+	return !now.Before(ep.downUntil)
+}
+
+// EndpointStatus is the state of an endpoint of a BalancedClient
+type EndpointStatus struct {
+	URL      string
+	Weight   int
+	Healthy  bool
+	Lagging  bool
+	Height   uint64
+	Failures int
+}
+
+// BalancedClient is a ClientInterface spreading the requests over several endpoints of the same service
+// in proportion to their weights. Relative request URLs are resolved against the chosen endpoint.
+//
+// An endpoint failing with a transport error, a 5xx or a 429 response is retried immediately on another
+// endpoint, and taken out of rotation for the cooldown after DefaultMaxFailures consecutive failures.
+// Health checks, run by Start, bring endpoints back and mark the ones lagging behind the others.
+// When every endpoint has been tried the request is retried again on all of them after a backoff,
+// up to the retry count.
+type BalancedClient struct {
+	endpoints []*endpointState
+	logger    hclog.Logger
+
+	lock sync.Mutex
+
+	healthCheck   HealthCheck
+	checkInterval time.Duration
+	maxLag        uint64
+	maxFailures   int
+	cooldown      time.Duration
+
+	retryCount       int // attempts after the first one, -1 is one attempt per endpoint
+	retryWaitTime    time.Duration
+	retryMaxWaitTime time.Duration
+}
+
+// NewBalancedClient creates a client of the endpoints, weights lower than 1 are 1
+func NewBalancedClient(endpoints []config.Endpoint, logger hclog.Logger) *BalancedClient {
+	l := logger
+	if l == nil {
+		l = hclog.Default()
+	}
+	clients := make([]*Client, len(endpoints))
+	for i, ep := range endpoints {
+		clients[i] = NewClientWithEndpoint(ep.URL, l)
+	}
+	return newBalancedClient(endpoints, clients, l)
+}
+
+func newBalancedClient(endpoints []config.Endpoint, clients []*Client, logger hclog.Logger) *BalancedClient {
+	c := &BalancedClient{
+		logger:           logger,
+		checkInterval:    DefaultHealthCheckInterval,
+		maxLag:           DefaultMaxLag,
+		maxFailures:      DefaultMaxFailures,
+		cooldown:         DefaultCooldown,
+		retryCount:       -1,
+		retryWaitTime:    defaultWaitTime,
+		retryMaxWaitTime: defaultMaxWaitTime,
+	}
+	for i, ep := range endpoints {
+		weight := ep.Weight
+		if weight < 1 {
+			weight = 1
+		}
+		c.endpoints = append(c.endpoints, &endpointState{url: ep.URL, weight: weight, client: clients[i]})
+	}
+	return c
+}
+
+// WithHealthCheck sets the check run by Start on every endpoint at the interval, DefaultHealthCheckInterval if 0
+func (c *BalancedClient) WithHealthCheck(check HealthCheck, interval time.Duration) *BalancedClient {
+	c.healthCheck = check
+	if interval > 0 {
+		c.checkInterval = interval
+	}
+	return c
+}
+
+// WithMaxLag sets how many blocks an endpoint may be behind the highest one, default is DefaultMaxLag
+func (c *BalancedClient) WithMaxLag(blocks uint64) *BalancedClient {
+	c.maxLag = blocks
+	return c
+}
+
+// WithMaxFailures sets the consecutive failures taking an endpoint out of rotation, default is DefaultMaxFailures
+func (c *BalancedClient) WithMaxFailures(failures int) *BalancedClient {
+	c.maxFailures = failures
+	return c
+}
+
+// WithCooldown sets how long a failing endpoint stays out of rotation, default is DefaultCooldown
+func (c *BalancedClient) WithCooldown(cooldown time.Duration) *BalancedClient {
+	c.cooldown = cooldown
+	return c
+}
+
+// WithRetryCount sets the attempts after the first one, they go to the other endpoints first.
+// The default is one attempt per endpoint.
+func (c *BalancedClient) WithRetryCount(retryCount uint) *BalancedClient {
+	c.retryCount = int(retryCount)
+	return c
+}
+
+// WithRetryWaitTime sets the wait time before retrying the endpoints once all of them have failed.
+//
+// Default is 100 milliseconds.
+func (c *BalancedClient) WithRetryWaitTime(retryWaitTime time.Duration) *BalancedClient {
+	c.retryWaitTime = retryWaitTime
+	return c
+}
+
+// WithRetryMaxWaitTime sets the max wait time before retrying the endpoints once all of them have failed.
+//
+// Default is 3 seconds.
+func (c *BalancedClient) WithRetryMaxWaitTime(retryMaxWaitTime time.Duration) *BalancedClient {
+	c.retryMaxWaitTime = retryMaxWaitTime
+	return c
+}
+
+// OnBeforeRequest appends the request middleware to the clients of all the endpoints
+func (c *BalancedClient) OnBeforeRequest(m RequestMiddleware) *BalancedClient {
+	for _, ep := range c.endpoints {
+		ep.client.OnBeforeRequest(m)
+	}
+	return c
+}
+
+// OnBeforeResponse appends the response middleware to the clients of all the endpoints
+func (c *BalancedClient) OnBeforeResponse(m ResponseMiddleware) *BalancedClient {
+	for _, ep := range c.endpoints {
+		ep.client.OnBeforeResponse(m)
+	}
+	return c
+}
+
+// AddHeaders adds the headers to the requests of all the endpoints
+func (c *BalancedClient) AddHeaders(header map[string]string) *BalancedClient {
+	for _, ep := range c.endpoints {
+		ep.client.AddHeaders(header)
+	}
+	return c
+}
+
+func (c *BalancedClient) Get(url string) *Request {
+	return NewRequest("GET", c).SetURL(url)
+}
+
+func (c *BalancedClient) Post(url string) *Request {
+	return NewRequest("POST", c).SetURL(url)
+}
+
+func (c *BalancedClient) Do(method, url string) *Request {
+	return NewRequest(method, c).SetURL(url)
+}
+
+func (c *BalancedClient) log() hclog.Logger {
+	return c.logger
+}
+
+func (c *BalancedClient) execute(r *Request) (*Response, error) {
+	if len(c.endpoints) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// the client of the endpoint resolves the URL and merges the headers into the request
+	origURL, origHeader := r.URL, r.Header.Clone()
+
+	attempts := c.retryCount + 1
+	if c.retryCount < 0 {
+		attempts = len(c.endpoints)
+	}
+
+	var (
+		resp  *Response
+		err   error
+		tried = make(map[*endpointState]bool, len(c.endpoints))
+		round uint
+	)
+	for attempt := 1; ; attempt++ {
+		if len(tried) == len(c.endpoints) {
+			// every endpoint failed, back off before the next round
+			tried = make(map[*endpointState]bool, len(c.endpoints))
+			select {
+			case <-time.After(jitterBackoff(c.retryWaitTime, c.retryMaxWaitTime, round)):
+			case <-ctx.Done():
+				return resp, ctx.Err()
+			}
+			round++
+		}
+
+		ep := c.pick(tried)
+		tried[ep] = true
+		r.URL, r.Header = origURL, origHeader.Clone()
+
+		resp, err = ep.client.execute(r)
+		if ctx.Err() != nil {
+			return resp, unwrapNoRetryErr(err)
+		}
+		if !shouldFailover(resp, err) {
+			c.succeeded(ep)
+			return resp, unwrapNoRetryErr(err)
+		}
+		c.failed(ep, resp, err)
+
+		if attempt >= attempts {
+			return resp, err
+		}
+		c.logger.Warn(fmt.Sprintf("%v, failing over, Attempt %v", err, attempt), "endpoint", redactURL(ep.url))
+	}
+}
+
+// shouldFailover reports whether the request failed because of the endpoint
+func shouldFailover(resp *Response, err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := err.(*noRetryErr); ok {
+		return false
+	}
+	// no status is a transport error
+	return isUnavailableStatus(statusOf(resp))
+}
+
+// isUnavailableStatus reports whether the status, 0 for a transport error, means the server can not serve now
+func isUnavailableStatus(status int) bool {
+	return status == 0 || status >= 500 || status == http.StatusTooManyRequests
+}
+
+func statusOf(resp *Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode()
+}
+
+// pick chooses by weight an endpoint not tried yet, preferring the available ones, then the lagging ones
+func (c *BalancedClient) pick(tried map[*endpointState]bool) *endpointState {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+	candidates := make([]*endpointState, 0, len(c.endpoints))
+	for _, accept := range []func(*endpointState) bool{
+		func(ep *endpointState) bool { return ep.available(now) && !ep.lagging },
+		func(ep *endpointState) bool { return ep.available(now) },
+		func(ep *endpointState) bool { return true },
+	} {
+		for _, ep := range c.endpoints {
+			if !tried[ep] && accept(ep) {
+				candidates = append(candidates, ep)
+			}
+		}
+		if len(candidates) > 0 {
+			break
+		}
+	}
+
+	total := 0
+	for _, ep := range candidates {
+		total += ep.weight
+	}
+	rndMu.Lock()
+	n := rnd.Intn(total)
+	rndMu.Unlock()
+	for _, ep := range candidates {
+		if n < ep.weight {
+			return ep
+		}
+		n -= ep.weight
+	}
+	return candidates[len(candidates)-1]
+}
+
+func (c *BalancedClient) succeeded(ep *endpointState) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	ep.failures = 0
+	ep.downUntil = time.Time{}
+}
+
+func (c *BalancedClient) failed(ep *endpointState, resp *Response, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	ep.failures++
+	if ep.failures >= c.maxFailures && ep.available(time.Now()) {
+		ep.downUntil = time.Now().Add(c.cooldown)
+		c.logger.Warn("endpoint taken out of rotation", "endpoint", redactURL(ep.url),
+			"status", statusOf(resp), "err", err, "cooldown", c.cooldown)
+	}
+}
+
+// Start runs the health check on every endpoint in the background, right away then at the interval
+// until ctx is done.
+// It does nothing without a health check, see WithHealthCheck.
+func (c *BalancedClient) Start(ctx context.Context) {
+	if c.healthCheck == nil {
+		return
+	}
+	go func() {
+		c.CheckHealth(ctx)
+		ticker := time.NewTicker(c.checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.CheckHealth(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// CheckHealth runs the health check once on every endpoint concurrently, and updates which ones are
+// out of rotation or lagging
+func (c *BalancedClient) CheckHealth(ctx context.Context) {
+	if c.healthCheck == nil {
+		return
+	}
+
+	heights := make([]uint64, len(c.endpoints))
+	errs := make([]error, len(c.endpoints))
+	var wg sync.WaitGroup
+	for i, ep := range c.endpoints {
+		wg.Add(1)
+		go func(i int, ep *endpointState) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, c.checkInterval)
+			defer cancel()
+			heights[i], errs[i] = c.healthCheck(checkCtx, ep.client)
+		}(i, ep)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var best uint64
+	for i, ep := range c.endpoints {
+		if errs[i] != nil {
+			if ep.available(time.Now()) {
+				c.logger.Warn("endpoint failed health check", "endpoint", redactURL(ep.url), "err", errs[i])
+			}
+			ep.downUntil = time.Now().Add(c.cooldown)
+			continue
+		}
+		ep.failures = 0
+		ep.downUntil = time.Time{}
+		ep.height = heights[i]
+		if ep.height > best {
+			best = ep.height
+		}
+	}
+	for i, ep := range c.endpoints {
+		lagging := errs[i] == nil && ep.height > 0 && best-ep.height > c.maxLag
+		if lagging && !ep.lagging {
+			c.logger.Warn("endpoint is lagging", "endpoint", redactURL(ep.url), "height", ep.height, "best", best)
+		}
+		ep.lagging = lagging
+	}
+}
+
+// Status returns the state of the endpoints
+func (c *BalancedClient) Status() []EndpointStatus {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+	status := make([]EndpointStatus, len(c.endpoints))
+	for i, ep := range c.endpoints {
+		status[i] = EndpointStatus{
+			URL:      ep.url,
+			Weight:   ep.weight,
+			Healthy:  ep.available(now),
+			Lagging:  ep.lagging,
+			Height:   ep.height,
+			Failures: ep.failures,
+		}
+	}
+	return status
+}
+
+// HTTPClient returns an http.Client balancing its requests over the endpoints the same way, for the
+// libraries which do their own HTTP, e.g. go-ethereum's rpc.DialOptions with rpc.WithHTTPClient.
+// The URL of the requests is replaced by the URL of the chosen endpoint.
+func (c *BalancedClient) HTTPClient() *http.Client {
+	return &http.Client{Transport: &balancedTransport{client: c}}
+}
+
+type balancedTransport struct {
+	client *BalancedClient
+}
+
+func (t *balancedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := t.client
+	if len(c.endpoints) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return nil, errors.New("request body can not be replayed on another endpoint")
+	}
+
+	tried := make(map[*endpointState]bool, len(c.endpoints))
+	for {
+		ep := c.pick(tried)
+		tried[ep] = true
+
+		epReq, err := endpointRequest(req, ep.url)
+		if err != nil {
+			return nil, err
+		}
+		web.SetTraceHeaders(epReq.Header, web.TraceIDFromContext(req.Context()))
+		transport := ep.client.httpClient.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+
+		resp, err := transport.RoundTrip(epReq)
+		if req.Context().Err() != nil {
+			return resp, err
+		}
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+		}
+		if !isUnavailableStatus(status) {
+			c.succeeded(ep)
+			return resp, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("endpoint responded %s", resp.Status)
+		}
+		c.failed(ep, nil, err)
+
+		if len(tried) == len(c.endpoints) {
+			// the last failure is returned as is, the response of an error status or the transport error
+			if resp != nil {
+				return resp, nil
+			}
+			return nil, err
+		}
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+		c.logger.Warn(fmt.Sprintf("%v, failing over", err), "endpoint", redactURL(ep.url))
+	}
+}
+
+func endpointRequest(req *http.Request, endpoint string) (*http.Request, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	epReq := req.Clone(req.Context())
+	epReq.URL = u
+	epReq.Host = u.Host
+	if req.GetBody != nil {
+		if epReq.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return epReq, nil
+}
+
+// redactURL strips the path and query of endpoint URLs, they often contain API keys
+func redactURL(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "<invalid url>"
+	}
+	return u.Scheme + "://" + u.Host
+}
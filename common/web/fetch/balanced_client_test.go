@@ -0,0 +1,189 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"crypto-trade-client/common/config"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEndpoint struct {
+	status int32 // response status, 0 is 200
+	hits   int32
+	url    string
+}
+
+func newTestEndpoints(t *testing.T, n int) []*testEndpoint {
+	eps := make([]*testEndpoint, n)
+	for i := range eps {
+		ep := &testEndpoint{}
+		ts := createTestServer(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&ep.hits, 1)
+			if status := atomic.LoadInt32(&ep.status); status != 0 {
+				w.WriteHeader(int(status))
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			_, _ = w.Write([]byte(r.URL.Path + ":" + string(body)))
+		})
+		t.Cleanup(ts.Close)
+		ep.url = ts.URL
+		eps[i] = ep
+	}
+	return eps
+}
+
+func newTestBalancedClient(eps []*testEndpoint, weights ...int) *BalancedClient {
+	endpoints := make([]config.Endpoint, len(eps))
+	for i, ep := range eps {
+		endpoints[i] = config.Endpoint{URL: ep.url}
+		if i < len(weights) {
+			endpoints[i].Weight = weights[i]
+		}
+	}
+	return NewBalancedClient(endpoints, hclog.NewNullLogger()).WithRetryWaitTime(time.Millisecond)
+}
+
+func Test_BalancedClient_weights(t *testing.T) {
+	eps := newTestEndpoints(t, 2)
+	c := newTestBalancedClient(eps, 3, 1)
+
+	for i := 0; i < 400; i++ {
+		resp, err := c.Post("/rpc").SetBody("x").Execute()
+		require.NoError(t, err)
+		assert.Equal(t, "/rpc:x", resp.String())
+	}
+	assert.InDelta(t, 300, atomic.LoadInt32(&eps[0].hits), 50)
+	assert.InDelta(t, 100, atomic.LoadInt32(&eps[1].hits), 50)
+}
+
+func Test_BalancedClient_failover(t *testing.T) {
+	eps := newTestEndpoints(t, 2)
+	atomic.StoreInt32(&eps[0].status, http.StatusServiceUnavailable)
+	c := newTestBalancedClient(eps, 100, 1)
+
+	for i := 0; i < 10; i++ {
+		resp, err := c.Get("/path").Execute()
+		require.NoError(t, err)
+		assert.Equal(t, "/path:", resp.String())
+	}
+	// the failing endpoint is out of rotation after DefaultMaxFailures failures
+	assert.EqualValues(t, DefaultMaxFailures, atomic.LoadInt32(&eps[0].hits))
+	assert.EqualValues(t, 10, atomic.LoadInt32(&eps[1].hits))
+
+	status := c.Status()
+	assert.False(t, status[0].Healthy)
+	assert.True(t, status[1].Healthy)
+
+	t.Run("client errors are not failed over", func(t *testing.T) {
+		atomic.StoreInt32(&eps[1].status, http.StatusBadRequest)
+		_, err := c.Get("/").Execute()
+		assert.Error(t, err)
+		assert.EqualValues(t, DefaultMaxFailures, atomic.LoadInt32(&eps[0].hits))
+		assert.True(t, c.Status()[1].Healthy)
+	})
+
+	t.Run("all endpoints down", func(t *testing.T) {
+		atomic.StoreInt32(&eps[1].status, http.StatusBadGateway)
+		resp, err := c.Get("/").Execute()
+		assert.Error(t, err)
+		// the endpoints out of rotation are tried last
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+		assert.EqualValues(t, DefaultMaxFailures+1, atomic.LoadInt32(&eps[0].hits))
+	})
+}
+
+func Test_BalancedClient_retries(t *testing.T) {
+	eps := newTestEndpoints(t, 2)
+	atomic.StoreInt32(&eps[0].status, http.StatusTooManyRequests)
+	atomic.StoreInt32(&eps[1].status, http.StatusInternalServerError)
+	c := newTestBalancedClient(eps).WithRetryCount(5).WithMaxFailures(100)
+
+	_, err := c.Get("/").Execute()
+	assert.Error(t, err)
+	// every round tries both endpoints
+	assert.EqualValues(t, 3, atomic.LoadInt32(&eps[0].hits))
+	assert.EqualValues(t, 3, atomic.LoadInt32(&eps[1].hits))
+
+	t.Run("transport error", func(t *testing.T) {
+		c := NewBalancedClient([]config.Endpoint{{URL: "http://127.0.0.1:1"}, {URL: eps[1].url}}, hclog.NewNullLogger())
+		atomic.StoreInt32(&eps[1].status, 0)
+		for i := 0; i < 5; i++ {
+			resp, err := c.Get("/ok").Execute()
+			require.NoError(t, err)
+			assert.Equal(t, "/ok:", resp.String())
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := c.Get("/").WithContext(ctx).Execute()
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
+}
+
+func Test_BalancedClient_health(t *testing.T) {
+	eps := newTestEndpoints(t, 3)
+	heights := map[string]uint64{eps[0].url: 100, eps[1].url: 90, eps[2].url: 0}
+	c := newTestBalancedClient(eps).WithHealthCheck(func(ctx context.Context, client *Client) (uint64, error) {
+		if client.host == eps[2].url {
+			return 0, errors.New("down")
+		}
+		return heights[client.host], nil
+	}, time.Minute)
+
+	c.CheckHealth(context.Background())
+	status := c.Status()
+	assert.Equal(t, EndpointStatus{URL: eps[0].url, Weight: 1, Healthy: true, Height: 100}, status[0])
+	assert.Equal(t, EndpointStatus{URL: eps[1].url, Weight: 1, Healthy: true, Lagging: true, Height: 90}, status[1])
+	assert.False(t, status[2].Healthy)
+
+	for i := 0; i < 10; i++ {
+		_, err := c.Get("/").Execute()
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 10, atomic.LoadInt32(&eps[0].hits))
+
+	// lagging endpoints are used when the others are down
+	atomic.StoreInt32(&eps[0].status, http.StatusServiceUnavailable)
+	resp, err := c.Get("/").Execute()
+	require.NoError(t, err)
+	assert.Equal(t, "/:", resp.String())
+	assert.EqualValues(t, 1, atomic.LoadInt32(&eps[1].hits))
+
+	// a passing check brings the endpoints back
+	heights[eps[1].url] = 100
+	c.CheckHealth(context.Background())
+	for _, s := range c.Status()[:2] {
+		assert.True(t, s.Healthy)
+		assert.False(t, s.Lagging)
+	}
+}
+
+func Test_BalancedClient_HTTPClient(t *testing.T) {
+	eps := newTestEndpoints(t, 2)
+	atomic.StoreInt32(&eps[0].status, http.StatusBadGateway)
+	c := newTestBalancedClient(eps, 100, 1)
+	hc := c.HTTPClient()
+
+	for i := 0; i < 5; i++ {
+		resp, err := hc.Post(eps[0].url+"/ignored", "text/plain", strings.NewReader("body"))
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "/:body", string(body))
+	}
+	assert.EqualValues(t, DefaultMaxFailures, atomic.LoadInt32(&eps[0].hits))
+}
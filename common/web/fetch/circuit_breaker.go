@@ -0,0 +1,145 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCircuitOpen is returned without sending the request when the circuit of the host is open
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+)
+
+// CircuitState is the state of the circuit of a host
+type CircuitState int
+
+const (
+	// CircuitClosed lets the requests through
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects the requests until the cooldown has elapsed
+	CircuitOpen
+	// CircuitHalfOpen lets a single probe request through, its outcome closes or re-opens the circuit
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// CircuitBreaker stops sending requests to a host after consecutive failures: transport errors,
+// 5xx and 429 responses. After the cooldown a probe request is let through, the circuit closes when it succeeds.
+type CircuitBreaker struct {
+	maxFailures int
+	cooldown    time.Duration
+
+	lock  sync.Mutex
+	hosts map[string]*circuit
+}
+
+// NewCircuitBreaker opens the circuit of a host after maxFailures consecutive failures for the cooldown.
+// maxFailures lower than 1 is 1.
+func NewCircuitBreaker(maxFailures int, cooldown time.Duration) *CircuitBreaker {
+	if maxFailures < 1 {
+		maxFailures = 1
+	}
+	return &CircuitBreaker{
+		maxFailures: maxFailures,
+		cooldown:    cooldown,
+		hosts:       make(map[string]*circuit),
+	}
+}
+
+// Allow returns ErrCircuitOpen when no request may be sent to the host now.
+// Every allowed request must be followed by Success or Failure.
+func (b *CircuitBreaker) Allow(host string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	c := b.circuitOf(host)
+	switch c.state {
+	case CircuitOpen:
+		if time.Since(c.openedAt) < b.cooldown {
+			return fmt.Errorf("%w: %s", ErrCircuitOpen, host)
+		}
+		c.state = CircuitHalfOpen
+		c.probing = true
+	case CircuitHalfOpen:
+		if c.probing {
+			return fmt.Errorf("%w: %s", ErrCircuitOpen, host)
+		}
+		c.probing = true
+	}
+	return nil
+}
+
+// Success records a request to the host which succeeded, it closes the circuit
+func (b *CircuitBreaker) Success(host string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	c := b.circuitOf(host)
+	c.state = CircuitClosed
+	c.failures = 0
+	c.probing = false
+}
+
+// Failure records a request to the host which failed, it opens the circuit after maxFailures
+// consecutive failures or when the probe of a half-open circuit fails
+func (b *CircuitBreaker) Failure(host string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	c := b.circuitOf(host)
+	c.failures++
+	if c.state == CircuitHalfOpen || c.failures >= b.maxFailures {
+		c.state = CircuitOpen
+		c.openedAt = time.Now()
+	}
+	c.probing = false
+}
+
+// State returns the state of the circuit of the host
+func (b *CircuitBreaker) State(host string) CircuitState {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	c := b.circuitOf(host)
+	if c.state == CircuitOpen && time.Since(c.openedAt) >= b.cooldown {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// abort releases the probe of a request which ended without telling anything of the host, e.g. canceled
+func (b *CircuitBreaker) abort(host string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.circuitOf(host).probing = false
+}
+
+func (b *CircuitBreaker) circuitOf(host string) *circuit {
+	c, ok := b.hosts[host]
+	if !ok {
+		c = &circuit{}
+		b.hosts[host] = c
+	}
+	return c
+}
@@ -0,0 +1,85 @@
+package fetch
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CircuitBreaker(t *testing.T) {
+	b := NewCircuitBreaker(2, 50*time.Millisecond)
+	const host = "node.example.com"
+
+	require.NoError(t, b.Allow(host))
+	b.Failure(host)
+	assert.Equal(t, CircuitClosed, b.State(host))
+	require.NoError(t, b.Allow(host))
+	b.Success(host)
+	require.NoError(t, b.Allow(host))
+	b.Failure(host)
+	require.NoError(t, b.Allow(host))
+	b.Failure(host)
+	assert.Equal(t, CircuitOpen, b.State(host))
+	assert.True(t, errors.Is(b.Allow(host), ErrCircuitOpen))
+	// the other hosts are not affected
+	assert.NoError(t, b.Allow("other.example.com"))
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, CircuitHalfOpen, b.State(host))
+	// a single probe at a time
+	require.NoError(t, b.Allow(host))
+	assert.True(t, errors.Is(b.Allow(host), ErrCircuitOpen))
+	b.Failure(host)
+	assert.Equal(t, CircuitOpen, b.State(host))
+
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, b.Allow(host))
+	b.Success(host)
+	assert.Equal(t, CircuitClosed, b.State(host))
+	assert.NoError(t, b.Allow(host))
+}
+
+func Test_RetryableClient_CircuitBreaker(t *testing.T) {
+	var hits, status int32
+	atomic.StoreInt32(&status, http.StatusTooManyRequests)
+	ts := createTestServer(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	})
+	defer ts.Close()
+
+	c := NewClientBuilder().
+		WithEndpoint(ts.URL).
+		WithLogger(hclog.NewNullLogger()).
+		WithRetryCount(10).
+		WithCircuitBreaker(3, 100*time.Millisecond).
+		BuildRetryable().
+		WithRetryWaitTime(time.Millisecond).
+		WithRetryMaxWaitTime(time.Millisecond).
+		AddRetryCondition(func(response *Response, err error) bool {
+			return response != nil && response.StatusCode() == http.StatusTooManyRequests
+		})
+
+	_, err := c.Get("/").Execute()
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.EqualValues(t, 3, atomic.LoadInt32(&hits))
+
+	// open: nothing is sent
+	_, err = c.Get("/").Execute()
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.EqualValues(t, 3, atomic.LoadInt32(&hits))
+
+	// half-open: the probe closes the circuit
+	atomic.StoreInt32(&status, http.StatusOK)
+	time.Sleep(110 * time.Millisecond)
+	resp, err := c.Get("/").Execute()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.EqualValues(t, 4, atomic.LoadInt32(&hits))
+}
@@ -12,6 +12,7 @@ import (
 	"net/url"
 	"regexp"
 	"sync"
+	"time"
 )
 
 var (
@@ -49,6 +50,8 @@ type Client struct {
 
 	beforeRequest  []RequestMiddleware
 	beforeResponse []ResponseMiddleware
+
+	metrics MetricsHook // nil uses the hook set by SetMetricsHook
 }
 
 func NewClient(logger hclog.Logger) *Client {
@@ -85,6 +88,9 @@ func (c *Client) OnBeforeRequest(m RequestMiddleware) *Client {
 	return c
 }
 
+// OnBeforeResponse method appends response middleware into the before response chain.
+// The middlewares run in order once the body is read, before the error status check, so that they can
+// rewrite the response with SetStatusCode and SetBody, or reject it by returning an error.
 func (c *Client) OnBeforeResponse(m ResponseMiddleware) *Client {
 	c.beforeResponse = append(c.beforeResponse, m)
 	return c
@@ -139,6 +145,7 @@ func (c *Client) createHttpRawRequest(r *Request) error {
 }
 
 func (c *Client) execute(r *Request) (*Response, error) {
+	r.attempt++
 
 	for _, m := range c.beforeRequest {
 		if err := m(c, r); err != nil {
@@ -154,6 +161,7 @@ func (c *Client) execute(r *Request) (*Response, error) {
 	if err != nil {
 		return nil, err
 	}
+	setTraceHeaders(r)
 	err = parseRequestBody(c, r)
 	if err != nil {
 		return nil, err
@@ -166,12 +174,14 @@ func (c *Client) execute(r *Request) (*Response, error) {
 		return nil, err
 	}
 
+	start := time.Now()
 	rawResponse, err := c.httpClient.Do(r.rawRequest)
 
 	response := &Response{
 		Request:     r,
 		rawResponse: rawResponse,
 	}
+	defer func() { c.observe(r, response, start, err) }()
 
 	if err != nil {
 		return response, err
@@ -184,6 +194,13 @@ func (c *Client) execute(r *Request) (*Response, error) {
 	}
 
 	_ = traceResponse(c, response)
+
+	for _, m := range c.beforeResponse {
+		if err = m(c, response); err != nil {
+			return response, err
+		}
+	}
+
 	err = handleError(c, response)
 	if err != nil {
 		return response, err
@@ -240,6 +257,20 @@ func parseRequestHeader(c *Client, r *Request) error {
 	return nil
 }
 
+// setTraceHeaders sends the trace id of the request, of its context, of its X-Request-Id header, or a new one
+func setTraceHeaders(r *Request) {
+	if r.traceId == "" {
+		r.traceId = web.TraceIDFromContext(r.ctx)
+	}
+	if r.traceId == "" {
+		r.traceId = web.TraceIDFromHeader(r.Header)
+	}
+	if r.traceId == "" {
+		r.traceId = web.NewTraceID()
+	}
+	web.SetTraceHeaders(r.Header, r.traceId)
+}
+
 func parseRequestBody(c *Client, r *Request) (err error) {
 	if r.Body != nil {
 		switch v := r.Body.(type) {
@@ -279,6 +310,7 @@ func traceRequest(c *Client, r *Request) error {
 		reqLog := "\n==============================================================================\n" +
 			"~~~ REQUEST ~~~\n" +
 			fmt.Sprintf("%s  %s\n", r.Method, r.URL) +
+			fmt.Sprintf("TRACE  : %s\n", r.traceId) +
 			fmt.Sprintf("HEADERS:\n%s\n", composeHeaders(rl.Header)) +
 			fmt.Sprintf("BODY   :\n%v\n", rl.Body) +
 			"------------------------------------------------------------------------------\n"
@@ -302,7 +334,7 @@ func traceResponse(c *Client, res *Response) error {
 		debugLog += fmt.Sprintf("BODY         :\n%v\n", rl.Body)
 		debugLog += "==============================================================================\n"
 
-		c.logger.Trace(debugLog)
+		c.logger.Trace(debugLog, web.TraceIDLogKey, res.Request.traceId)
 	}
 
 	return nil
@@ -310,7 +342,7 @@ func traceResponse(c *Client, res *Response) error {
 
 func handleError(c *Client, r *Response) (err error) {
 	if r.IsError() {
-		c.logger.Warn("got error response", "statusCode", r.StatusCode())
+		c.logger.Warn("got error response", "statusCode", r.StatusCode(), web.TraceIDLogKey, r.Request.traceId)
 		return web.ServerErrorCtor(r.StatusCode(), r.fmtBodyString(c.traceBodySizeLimit))
 	}
 	return nil
@@ -14,11 +14,17 @@ import (
 
 type ClientBuilder struct {
 	endpoint           string
+	endpoints          []config.Endpoint
 	logger             hclog.Logger
 	traceBodySizeLimit int64
 
 	tlsConfig *tls.Config
 	header    map[string]string
+
+	// used by BuildRetryable
+	retryCount uint
+	limiter    *RateLimiter
+	breaker    *CircuitBreaker
 }
 
 func NewClientBuilder() *ClientBuilder {
@@ -30,6 +36,12 @@ func (cb *ClientBuilder) WithEndpoint(endpoint string) *ClientBuilder {
 	return cb
 }
 
+// WithEndpoints sets the endpoints of the client built by BuildBalanced
+func (cb *ClientBuilder) WithEndpoints(endpoints ...config.Endpoint) *ClientBuilder {
+	cb.endpoints = endpoints
+	return cb
+}
+
 func (cb *ClientBuilder) WithLogger(logger hclog.Logger) *ClientBuilder {
 	cb.logger = logger
 	return cb
@@ -70,7 +82,63 @@ func (cb *ClientBuilder) WithHeaders(header map[string]string) *ClientBuilder {
 	return cb
 }
 
+// WithRetryCount sets the retry count of the client built by BuildRetryable
+func (cb *ClientBuilder) WithRetryCount(retryCount uint) *ClientBuilder {
+	cb.retryCount = retryCount
+	return cb
+}
+
+// WithRateLimit limits the client built by BuildRetryable to requestsPerSecond to every host,
+// with bursts of up to burst requests. A requestsPerSecond of 0 is no limit.
+func (cb *ClientBuilder) WithRateLimit(requestsPerSecond float64, burst int) *ClientBuilder {
+	cb.limiter = nil
+	if requestsPerSecond > 0 {
+		cb.limiter = NewRateLimiter(requestsPerSecond, burst)
+	}
+	return cb
+}
+
+// WithCircuitBreaker makes the client built by BuildRetryable stop sending requests to a host
+// after maxFailures consecutive failures, until the cooldown has elapsed. A maxFailures of 0 is no breaker.
+func (cb *ClientBuilder) WithCircuitBreaker(maxFailures int, cooldown time.Duration) *ClientBuilder {
+	cb.breaker = nil
+	if maxFailures > 0 {
+		cb.breaker = NewCircuitBreaker(maxFailures, cooldown)
+	}
+	return cb
+}
+
+// BuildRetryable builds a RetryableClient with the retry count, rate limit and circuit breaker of the builder
+func (cb *ClientBuilder) BuildRetryable() *RetryableClient {
+	return NewRetryableClient(cb.Build()).
+		WithRetryCount(cb.retryCount).
+		WithRateLimiter(cb.limiter).
+		WithCircuitBreaker(cb.breaker)
+}
+
 func (cb *ClientBuilder) Build() *Client {
+	return cb.build(cb.endpoint)
+}
+
+// BuildBalanced builds a client of every endpoint set by WithEndpoints, with the same TLS config and headers,
+// and balances the requests over them. The endpoint set by WithEndpoint is used when there are no endpoints.
+func (cb *ClientBuilder) BuildBalanced() *BalancedClient {
+	endpoints := cb.endpoints
+	if len(endpoints) == 0 && cb.endpoint != "" {
+		endpoints = []config.Endpoint{{URL: cb.endpoint, Weight: 1}}
+	}
+	clients := make([]*Client, len(endpoints))
+	for i, ep := range endpoints {
+		clients[i] = cb.build(ep.URL)
+	}
+	logger := cb.logger
+	if logger == nil {
+		logger = hclog.Default()
+	}
+	return newBalancedClient(endpoints, clients, logger)
+}
+
+func (cb *ClientBuilder) build(endpoint string) *Client {
 	hc := &http.Client{}
 	if cb.tlsConfig != nil {
 		hc.Transport = &http.Transport{
@@ -86,7 +154,7 @@ func (cb *ClientBuilder) Build() *Client {
 			TLSClientConfig:       cb.tlsConfig,
 		}
 	}
-	c := NewClientWithCustomHttpClient(hc, cb.endpoint, cb.logger)
+	c := NewClientWithCustomHttpClient(hc, endpoint, cb.logger)
 
 	c.AddHeaders(cb.header)
 
@@ -1,6 +1,8 @@
 package fetch
 
 import (
+	"compress/gzip"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -9,6 +11,8 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/hashicorp/go-hclog"
 )
 
 func createTestServer(fn func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
@@ -113,3 +117,68 @@ func createGetServer(t *testing.T) *httptest.Server {
 
 	return ts
 }
+
+func Test_ClientOnBeforeResponse(t *testing.T) {
+	ts := createTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rate-limited":
+			_, _ = w.Write([]byte(`{"error":{"code":-32005,"message":"daily request count exceeded"}}`))
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			_, _ = zw.Write([]byte("unzipped"))
+			_ = zw.Close()
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	})
+	defer ts.Close()
+
+	var calls []string
+	c := NewClientWithEndpoint(ts.URL, hclog.NewNullLogger()).
+		OnBeforeResponse(func(c *Client, r *Response) error {
+			calls = append(calls, "first:"+r.String())
+			if strings.Contains(r.String(), "request count exceeded") {
+				r.SetStatusCode(http.StatusTooManyRequests)
+			}
+			return nil
+		}).
+		OnBeforeResponse(func(c *Client, r *Response) error {
+			calls = append(calls, fmt.Sprintf("second:%d", r.StatusCode()))
+			return nil
+		})
+
+	resp, err := c.Get("/").Execute()
+	if err != nil || resp.String() != "ok" {
+		t.Fatalf("unexpected response %q, %v", resp.String(), err)
+	}
+	if strings.Join(calls, ",") != "first:ok,second:200" {
+		t.Errorf("middlewares not called in order: %v", calls)
+	}
+
+	resp, err = c.Get("/rate-limited").Execute()
+	if err == nil || resp.StatusCode() != http.StatusTooManyRequests {
+		t.Errorf("rewritten status not checked: %d, %v", resp.StatusCode(), err)
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		rejected := errors.New("rejected")
+		c := NewClientWithEndpoint(ts.URL, hclog.NewNullLogger()).
+			OnBeforeResponse(func(c *Client, r *Response) error { return rejected })
+		_, err := c.Get("/").Execute()
+		if !errors.Is(err, rejected) {
+			t.Errorf("expected rejected, got %v", err)
+		}
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		c := NewClientWithEndpoint(ts.URL, hclog.NewNullLogger()).OnBeforeResponse(GunzipBody)
+		resp, err := c.Get("/gzip").SetHeader("Accept-Encoding", "gzip").Execute()
+		if err != nil || resp.String() != "unzipped" {
+			t.Errorf("unexpected response %q, %v", resp.String(), err)
+		}
+		if resp.Header().Get("Content-Encoding") != "" {
+			t.Errorf("Content-Encoding not removed")
+		}
+	})
+}
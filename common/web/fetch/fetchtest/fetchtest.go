@@ -0,0 +1,46 @@
+// Package fetchtest records and replays the HTTP traffic of tests, see fetch.Recorder.
+package fetchtest
+
+import (
+	"crypto-trade-client/common/web/fetch"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+// FixtureDir is the directory of the fixtures, relative to the package of the test
+const FixtureDir = "testdata/fixtures"
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// FixturePath returns the fixture file of the test
+func FixturePath(t testing.TB) string {
+	return filepath.Join(FixtureDir, unsafeChars.ReplaceAllString(t.Name(), "_")+".json")
+}
+
+// Replay returns a recorder replaying, or recording according to fetch.RecorderModeFromEnv, the requests of
+// the test. The clients under test send their requests through it, e.g. with the http.Client of
+// Recorder.HTTPClient; the transport of the other clients is left alone. A recorded fixture is saved when
+// the test ends.
+//
+// The fixtures are committed under the testdata/fixtures of the package, re-record them with:
+//
+//	make record-fixtures
+func Replay(t testing.TB) *fetch.Recorder {
+	t.Helper()
+
+	recorder, err := fetch.NewRecorder(FixturePath(t), fetch.RecorderModeFromEnv(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if t.Failed() {
+			// a partial recording would fail on replay
+			return
+		}
+		if err := recorder.Save(); err != nil {
+			t.Errorf("error saving fixture: %v", err)
+		}
+	})
+	return recorder
+}
@@ -0,0 +1,64 @@
+package fetch
+
+import (
+	"sync"
+	"time"
+)
+
+// RequestMetrics describes an attempt of a request sent by a Client
+type RequestMetrics struct {
+	Host     string
+	Method   string
+	Status   int // 0 when no response was received
+	Attempt  int // 1 for the first attempt, retries and failovers count up
+	Duration time.Duration
+	Err      error
+}
+
+// MetricsHook observes the requests of the clients, e.g. metrics.Prometheus.
+// It is called synchronously by every request, it must be fast and safe for concurrent use.
+type MetricsHook interface {
+	ObserveRequest(m RequestMetrics)
+}
+
+var (
+	metricsHookLock    sync.RWMutex
+	defaultMetricsHook MetricsHook
+)
+
+// SetMetricsHook sets the hook of the clients without their own, nil stops observing them
+func SetMetricsHook(hook MetricsHook) {
+	metricsHookLock.Lock()
+	defer metricsHookLock.Unlock()
+	defaultMetricsHook = hook
+}
+
+func (c *Client) metricsHook() MetricsHook {
+	if c.metrics != nil {
+		return c.metrics
+	}
+	metricsHookLock.RLock()
+	defer metricsHookLock.RUnlock()
+	return defaultMetricsHook
+}
+
+// WithMetricsHook sets the hook observing the requests of the client instead of the one set by SetMetricsHook
+func (c *Client) WithMetricsHook(hook MetricsHook) *Client {
+	c.metrics = hook
+	return c
+}
+
+func (c *Client) observe(r *Request, resp *Response, start time.Time, err error) {
+	hook := c.metricsHook()
+	if hook == nil {
+		return
+	}
+	hook.ObserveRequest(RequestMetrics{
+		Host:     r.rawRequest.URL.Host,
+		Method:   r.Method,
+		Status:   statusOf(resp),
+		Attempt:  r.attempt,
+		Duration: time.Since(start),
+		Err:      err,
+	})
+}
@@ -0,0 +1,79 @@
+package fetch
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingHook struct {
+	lock     sync.Mutex
+	requests []RequestMetrics
+}
+
+func (h *recordingHook) ObserveRequest(m RequestMetrics) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.requests = append(h.requests, m)
+}
+
+func Test_ClientMetricsHook(t *testing.T) {
+	var calls int32
+	ts := createTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+	defer ts.Close()
+
+	hook := &recordingHook{}
+	c := NewRetryableClient(NewClient(hclog.L()).WithMetricsHook(hook)).WithRetryCount(2)
+	_, err := c.Get(ts.URL + "/metrics").Execute()
+	require.NoError(t, err)
+
+	host := mustHost(t, ts.URL)
+	require.Len(t, hook.requests, 2)
+	assert.Equal(t, host, hook.requests[0].Host)
+	assert.Equal(t, http.MethodGet, hook.requests[0].Method)
+	assert.Equal(t, http.StatusServiceUnavailable, hook.requests[0].Status)
+	assert.Equal(t, 1, hook.requests[0].Attempt)
+	assert.Error(t, hook.requests[0].Err)
+	assert.Equal(t, http.StatusOK, hook.requests[1].Status)
+	assert.Equal(t, 2, hook.requests[1].Attempt)
+	assert.NoError(t, hook.requests[1].Err)
+}
+
+func Test_SetMetricsHook(t *testing.T) {
+	ts := createGetServer(t)
+	defer ts.Close()
+
+	hook := &recordingHook{}
+	SetMetricsHook(hook)
+	defer SetMetricsHook(nil)
+
+	_, err := NewClient(hclog.L()).Get(ts.URL + "/").Execute()
+	require.NoError(t, err)
+	require.Len(t, hook.requests, 1)
+	assert.Equal(t, http.StatusOK, hook.requests[0].Status)
+
+	// a client hook replaces the global one
+	own := &recordingHook{}
+	_, err = NewClient(hclog.L()).WithMetricsHook(own).Get(ts.URL + "/").Execute()
+	require.NoError(t, err)
+	assert.Len(t, hook.requests, 1)
+	assert.Len(t, own.requests, 1)
+}
+
+func mustHost(t *testing.T, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+	return u.Host
+}
@@ -1,5 +1,13 @@
 package fetch
 
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
 type (
 	// RequestMiddleware type is for request middleware, called before a request is sent
 	RequestMiddleware func(*Client, *Request) error
@@ -7,3 +15,25 @@ type (
 	// ResponseMiddleware type is for response middleware, called after a response has been received
 	ResponseMiddleware func(*Client, *Response) error
 )
+
+// GunzipBody is a response middleware decoding gzip bodies. The transport only decodes them by itself
+// when the request does not set Accept-Encoding.
+func GunzipBody(c *Client, r *Response) error {
+	if !strings.EqualFold(r.Header().Get(hdrContentEncodingKey), "gzip") || len(r.bodyBytes) == 0 {
+		return nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(r.bodyBytes))
+	if err != nil {
+		return fmt.Errorf("invalid gzip body: %v", err)
+	}
+	defer zr.Close()
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		return fmt.Errorf("invalid gzip body: %v", err)
+	}
+
+	r.SetBody(body)
+	r.Header().Del(hdrContentEncodingKey)
+	r.Header().Del(hdrContentLengthKey)
+	return nil
+}
@@ -0,0 +1,46 @@
+package fetch
+
+import (
+	"context"
+	"golang.org/x/time/rate"
+	"sync"
+)
+
+// RateLimiter limits the requests sent to every host with a token bucket,
+// e.g. to stay under the requests per second of a provider's plan
+type RateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	lock  sync.Mutex
+	hosts map[string]*rate.Limiter
+}
+
+// NewRateLimiter allows requestsPerSecond to every host, with bursts of up to burst requests.
+// burst lower than 1 is 1.
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		limit: rate.Limit(requestsPerSecond),
+		burst: burst,
+		hosts: make(map[string]*rate.Limiter),
+	}
+}
+
+// Wait blocks until a request can be sent to the host, or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context, host string) error {
+	return l.limiterOf(host).Wait(ctx)
+}
+
+func (l *RateLimiter) limiterOf(host string) *rate.Limiter {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	limiter, ok := l.hosts[host]
+	if !ok {
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.hosts[host] = limiter
+	}
+	return limiter
+}
@@ -0,0 +1,67 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RateLimiter(t *testing.T) {
+	l := NewRateLimiter(20, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		require.NoError(t, l.Wait(ctx, "a"))
+	}
+	// 2 at once then 4 at 20/s
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, int64(elapsed), int64(180*time.Millisecond))
+	assert.Less(t, int64(elapsed), int64(400*time.Millisecond))
+
+	// the buckets are per host
+	start = time.Now()
+	require.NoError(t, l.Wait(ctx, "b"))
+	assert.Less(t, int64(time.Since(start)), int64(10*time.Millisecond))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Error(t, l.Wait(canceled, "a"))
+}
+
+func Test_RetryableClient_RateLimit(t *testing.T) {
+	var hits int32
+	ts := createTestServer(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	})
+	defer ts.Close()
+
+	c := NewClientBuilder().
+		WithEndpoint(ts.URL).
+		WithLogger(hclog.NewNullLogger()).
+		WithRateLimit(50, 5).
+		BuildRetryable()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 15; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Get("/").Execute()
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 15, atomic.LoadInt32(&hits))
+	// 5 at once then 10 at 50/s
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(190*time.Millisecond))
+}
@@ -0,0 +1,363 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RecorderModeEnv is the environment variable selecting the RecorderMode of RecorderModeFromEnv:
+// "record" or "missing", anything else is ReplayOnly so that tests never reach live endpoints unless asked to
+const RecorderModeEnv = "FETCH_RECORDER"
+
+// RecorderMode tells a Recorder whether to replay its fixture or to record a new one
+type RecorderMode int
+
+const (
+	// RecordMissing replays the fixture when it exists, and records it otherwise
+	RecordMissing RecorderMode = iota
+	// RecordAlways sends every request and overwrites the fixture
+	RecordAlways
+	// ReplayOnly never sends a request, the fixture must exist
+	ReplayOnly
+)
+
+var (
+	// ErrNotRecorded is returned on replay for a request missing from the fixture
+	ErrNotRecorded = errors.New("request not recorded")
+)
+
+// RecorderModeFromEnv returns the mode set by RecorderModeEnv
+func RecorderModeFromEnv() RecorderMode {
+	switch strings.ToLower(os.Getenv(RecorderModeEnv)) {
+	case "record":
+		return RecordAlways
+	case "missing":
+		return RecordMissing
+	default:
+		return ReplayOnly
+	}
+}
+
+type recordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type recordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+type interaction struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+
+	key  string
+	used bool
+}
+
+type fixture struct {
+	Interactions []*interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper recording the request/response pairs into a JSON fixture file,
+// and replaying them so that tests run offline and deterministically. Use it as the transport of the
+// http.Client of a fetch.Client or of go-ethereum's rpc client, see HTTPClient.
+//
+// Requests are matched by method, URL and body. The ids of JSON-RPC messages are ignored, the replayed
+// responses get the ids of the requests. A request sent several times gets the recorded responses in order,
+// then the last one again, e.g. when polling.
+type Recorder struct {
+	path      string
+	replaying bool
+	transport http.RoundTripper
+	ignored   map[string]bool
+
+	lock     sync.Mutex
+	recorded fixture
+}
+
+// NewRecorder creates a recorder of the fixture file. transport sends the requests when recording,
+// nil is http.DefaultTransport.
+func NewRecorder(path string, mode RecorderMode, transport http.RoundTripper) (*Recorder, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	r := &Recorder{path: path, transport: transport, ignored: make(map[string]bool)}
+
+	if mode == RecordAlways {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if mode == RecordMissing && errors.Is(err, os.ErrNotExist) {
+			return r, nil
+		}
+		return nil, fmt.Errorf("error reading fixture: %v", err)
+	}
+	if err = json.Unmarshal(data, &r.recorded); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %v", path, err)
+	}
+	r.replaying = true
+	return r, nil
+}
+
+// IgnoreFields ignores the fields of the JSON bodies with the names at any depth when matching requests,
+// e.g. random ids of requests which are not JSON-RPC ids
+func (r *Recorder) IgnoreFields(names ...string) *Recorder {
+	for _, name := range names {
+		r.ignored[name] = true
+	}
+	for _, i := range r.recorded.Interactions {
+		i.key = ""
+	}
+	return r
+}
+
+// Replaying reports whether the recorder replays its fixture
+func (r *Recorder) Replaying() bool {
+	return r.replaying
+}
+
+// HTTPClient returns an http.Client using the recorder as transport
+func (r *Recorder) HTTPClient() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.replaying {
+		return r.replay(req, body)
+	}
+	return r.record(req, body)
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	key := r.keyOf(req.Method, req.URL.String(), body)
+
+	r.lock.Lock()
+	var found *interaction
+	for _, i := range r.recorded.Interactions {
+		if i.key == "" {
+			i.key = r.keyOf(i.Request.Method, i.Request.URL, []byte(i.Request.Body))
+		}
+		if i.key != key {
+			continue
+		}
+		found = i
+		if !i.used {
+			break
+		}
+	}
+	if found != nil {
+		found.used = true
+	}
+	r.lock.Unlock()
+
+	if found == nil {
+		return nil, fmt.Errorf("%w in %s: %s %s %s", ErrNotRecorded, r.path, req.Method, req.URL, body)
+	}
+
+	respBody := replaceJsonrpcIDs([]byte(found.Response.Body), []byte(found.Request.Body), body)
+	header := found.Response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", found.Response.Status, http.StatusText(found.Response.Status)),
+		StatusCode:    found.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	header := http.Header{}
+	for _, k := range []string{hdrContentTypeKey, hdrContentEncodingKey, "Retry-After"} {
+		if v := resp.Header.Values(k); len(v) > 0 {
+			header[k] = v
+		}
+	}
+
+	r.lock.Lock()
+	r.recorded.Interactions = append(r.recorded.Interactions, &interaction{
+		Request:  recordedRequest{Method: req.Method, URL: req.URL.String(), Body: string(body)},
+		Response: recordedResponse{Status: resp.StatusCode, Header: header, Body: string(respBody)},
+	})
+	r.lock.Unlock()
+
+	return resp, nil
+}
+
+// Save writes the recorded interactions into the fixture, it does nothing when replaying
+func (r *Recorder) Save() error {
+	if r.replaying {
+		return nil
+	}
+	r.lock.Lock()
+	data, err := json.MarshalIndent(&r.recorded, "", "  ")
+	r.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// keyOf returns the key matching the requests, JSON bodies are compared without the JSON-RPC ids
+// and the ignored fields
+func (r *Recorder) keyOf(method string, url string, body []byte) string {
+	var v interface{}
+	if len(body) > 0 && json.Unmarshal(body, &v) == nil {
+		v = stripJsonrpcIDs(v)
+		v = stripFields(v, r.ignored)
+		if normalized, err := json.Marshal(v); err == nil {
+			body = normalized
+		}
+	}
+	return method + " " + url + "\n" + string(body)
+}
+
+func isJsonrpcMessage(m map[string]interface{}) bool {
+	_, hasMethod := m["method"]
+	_, hasVersion := m["jsonrpc"]
+	return hasMethod || hasVersion
+}
+
+func stripJsonrpcIDs(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if isJsonrpcMessage(t) {
+			delete(t, "id")
+		}
+	case []interface{}:
+		for _, e := range t {
+			if m, ok := e.(map[string]interface{}); ok && isJsonrpcMessage(m) {
+				delete(m, "id")
+			}
+		}
+	}
+	return v
+}
+
+func stripFields(v interface{}, names map[string]bool) interface{} {
+	if len(names) == 0 {
+		return v
+	}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			if names[k] {
+				delete(t, k)
+				continue
+			}
+			t[k] = stripFields(e, names)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = stripFields(e, names)
+		}
+	}
+	return v
+}
+
+// jsonrpcIDs returns the ids of the JSON-RPC messages of the body, in order
+func jsonrpcIDs(body []byte) []json.RawMessage {
+	var single struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(body, &single) == nil && single.ID != nil {
+		return []json.RawMessage{single.ID}
+	}
+	var batch []struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(body, &batch) != nil {
+		return nil
+	}
+	ids := make([]json.RawMessage, len(batch))
+	for i, m := range batch {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+// replaceJsonrpcIDs replaces the ids of the recorded request in the response by the ids of the replayed request
+func replaceJsonrpcIDs(respBody []byte, recordedReq []byte, req []byte) []byte {
+	recordedIDs, ids := jsonrpcIDs(recordedReq), jsonrpcIDs(req)
+	if len(recordedIDs) == 0 || len(recordedIDs) != len(ids) {
+		return respBody
+	}
+	replacements := make(map[string]json.RawMessage, len(ids))
+	for i, id := range recordedIDs {
+		replacements[string(id)] = ids[i]
+	}
+	replace := func(m map[string]json.RawMessage) {
+		if id, ok := replacements[string(m["id"])]; ok {
+			m["id"] = id
+		}
+	}
+
+	var single map[string]json.RawMessage
+	if json.Unmarshal(respBody, &single) == nil {
+		replace(single)
+		if out, err := json.Marshal(single); err == nil {
+			return out
+		}
+		return respBody
+	}
+	var batch []map[string]json.RawMessage
+	if json.Unmarshal(respBody, &batch) == nil {
+		for _, m := range batch {
+			replace(m)
+		}
+		if out, err := json.Marshal(batch); err == nil {
+			return out
+		}
+	}
+	return respBody
+}
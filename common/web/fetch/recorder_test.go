@@ -0,0 +1,114 @@
+package fetch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Recorder(t *testing.T) {
+	var height int32
+	ts := createTestServer(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]json.RawMessage
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &req)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"%s:%d"}`, req["id"], r.URL.Path, atomic.AddInt32(&height, 1))
+	})
+	fixture := filepath.Join(t.TempDir(), "fixtures", "recorder.json")
+
+	call := func(c *Client, id int, path string) (string, error) {
+		resp, err := c.Post(path).SetJSONBody(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": "height"}).Execute()
+		if err != nil {
+			return "", err
+		}
+		var msg struct {
+			ID     int    `json:"id"`
+			Result string `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(resp.BodyBytes(), &msg))
+		assert.Equal(t, id, msg.ID)
+		return msg.Result, nil
+	}
+
+	recorder, err := NewRecorder(fixture, RecordMissing, nil)
+	require.NoError(t, err)
+	assert.False(t, recorder.Replaying())
+	c := NewClientWithCustomHttpClient(recorder.HTTPClient(), ts.URL, hclog.NewNullLogger())
+	for i, want := range []string{"/a:1", "/a:2", "/b:3"} {
+		path := "/a"
+		if i == 2 {
+			path = "/b"
+		}
+		got, err := call(c, i+1, path)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	require.NoError(t, recorder.Save())
+	ts.Close()
+
+	recorder, err = NewRecorder(fixture, RecordMissing, nil)
+	require.NoError(t, err)
+	assert.True(t, recorder.Replaying())
+	c = NewClientWithCustomHttpClient(recorder.HTTPClient(), ts.URL, hclog.NewNullLogger())
+
+	// the responses are replayed in order with the ids of the requests, the last one repeats
+	for i, want := range []string{"/b:3", "/a:1", "/a:2", "/a:2"} {
+		path := "/a"
+		if i == 0 {
+			path = "/b"
+		}
+		got, err := call(c, 100+i, path)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	_, err = call(c, 1, "/c")
+	assert.True(t, errors.Is(err, ErrNotRecorded))
+
+	_, err = NewRecorder(filepath.Join(t.TempDir(), "missing.json"), ReplayOnly, nil)
+	assert.Error(t, err)
+}
+
+func Test_Recorder_IgnoreFields(t *testing.T) {
+	fixture := filepath.Join(t.TempDir(), "recorder.json")
+	require.NoError(t, os.WriteFile(fixture, []byte(`{"interactions":[{
+		"request":{"method":"POST","url":"http://node/","body":"{\"method\":\"ledger\",\"params\":[{\"id\":\"a\",\"ledger_index\":1}]}"},
+		"response":{"status":200,"body":"ok"}
+	}]}`), 0o644))
+
+	recorder, err := NewRecorder(fixture, ReplayOnly, nil)
+	require.NoError(t, err)
+	c := NewClientWithCustomHttpClient(recorder.HTTPClient(), "http://node", hclog.NewNullLogger())
+	body := `{"method":"ledger","params":[{"id":"b","ledger_index":1}]}`
+
+	_, err = c.Post("/").SetBody(body).Execute()
+	assert.True(t, errors.Is(err, ErrNotRecorded))
+
+	recorder.IgnoreFields("id")
+	resp, err := c.Post("/").SetBody(body).Execute()
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.String())
+}
+
+func Test_RecorderModeFromEnv(t *testing.T) {
+	t.Setenv(RecorderModeEnv, "record")
+	assert.Equal(t, RecordAlways, RecorderModeFromEnv())
+	t.Setenv(RecorderModeEnv, "Missing")
+	assert.Equal(t, RecordMissing, RecorderModeFromEnv())
+	t.Setenv(RecorderModeEnv, "replay")
+	assert.Equal(t, ReplayOnly, RecorderModeFromEnv())
+	// the tests replay by default
+	t.Setenv(RecorderModeEnv, "")
+	assert.Equal(t, ReplayOnly, RecorderModeFromEnv())
+}
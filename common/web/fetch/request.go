@@ -22,8 +22,9 @@ type Request struct {
 
 	rawRequest *http.Request
 	ctx        context.Context
+	attempt    int // attempts sent by the clients, counting retries and failovers
 
-	// used for request tracing
+	// used for request tracing, the same id is sent by all the attempts
 	traceId string
 	values  map[string]interface{}
 	// used for `REQUEST` shortcut
@@ -39,11 +40,18 @@ func NewRequest(method string, client ClientInterface) *Request {
 	}
 }
 
+// SetTrace sets the trace id sent in the X-Request-Id and traceparent headers and logged with the request.
+// Without it the trace id is taken from the context set by WithContext, or generated.
 func (r *Request) SetTrace(traceId string) *Request {
 	r.traceId = traceId
 	return r
 }
 
+// TraceId returns the trace id of the request, set once it is sent
+func (r *Request) TraceId() string {
+	return r.traceId
+}
+
 func (r *Request) SetURL(url string) *Request {
 	r.URL = url
 	return r
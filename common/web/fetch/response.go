@@ -45,6 +45,21 @@ func (r *Response) IsError() bool {
 	return r.StatusCode() >= 400
 }
 
+// SetStatusCode replaces the status code of the response, e.g. by a response middleware turning
+// a rate limit error delivered with 200 into a 429
+func (r *Response) SetStatusCode(code int) {
+	if r.rawResponse == nil {
+		return
+	}
+	r.rawResponse.StatusCode = code
+	r.rawResponse.Status = fmt.Sprintf("%d %s", code, http.StatusText(code))
+}
+
+// SetBody replaces the body of the response, e.g. by a response middleware decoding it
+func (r *Response) SetBody(body []byte) {
+	r.bodyBytes = body
+}
+
 func (r *Response) Parse(v interface{}) error {
 	if r.bodyBytes == nil || !IsJSONType(r.Header().Get(hdrContentTypeKey)) {
 		return ErrNotParsableContent
@@ -7,11 +7,13 @@ package fetch
 
 import (
 	"context"
+	"crypto-trade-client/common/web"
 	"fmt"
 	"github.com/hashicorp/go-hclog"
 	"math"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"strconv"
 	"sync"
 	"time"
@@ -50,6 +52,10 @@ type RetryableClient struct {
 	retryConditions  []RetryConditionFunc
 	retryAfter       RetryAfterFunc
 
+	// optional, every attempt waits for the limiter and is rejected by an open breaker
+	limiter *RateLimiter
+	breaker *CircuitBreaker
+
 	client *Client
 }
 
@@ -98,16 +104,16 @@ func (c *RetryableClient) execute(r *Request) (*Response, error) {
 	if c.retryCount == 0 {
 		// do not retry
 		attempt = 1
-		resp, err = c.client.execute(r)
+		resp, err = c.send(r)
 		return resp, unwrapNoRetryErr(err)
 	}
 
 	err = exponentialBackoff(
 		func() (*Response, error) {
 			attempt++
-			resp, err = c.client.execute(r)
+			resp, err = c.send(r)
 			if err != nil {
-				r.client.log().Warn(fmt.Sprintf("%v, Attempt %v", err, attempt))
+				r.client.log().Warn(fmt.Sprintf("%v, Attempt %v", err, attempt), web.TraceIDLogKey, r.traceId)
 			}
 			return resp, err
 		},
@@ -118,9 +124,61 @@ func (c *RetryableClient) execute(r *Request) (*Response, error) {
 		RetryAfter(c.retryAfter),
 	)
 
+	return resp, unwrapNoRetryErr(err)
+}
+
+// send sends a single attempt of the request through the rate limiter and the circuit breaker,
+// their errors are not retried
+func (c *RetryableClient) send(r *Request) (*Response, error) {
+	if c.limiter == nil && c.breaker == nil {
+		return c.client.execute(r)
+	}
+
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	host := c.hostOf(r)
+
+	if c.breaker != nil {
+		if err := c.breaker.Allow(host); err != nil {
+			return nil, wrapNoRetryErr(err)
+		}
+	}
+	if c.limiter != nil {
+		if err := c.limiter.Wait(ctx, host); err != nil {
+			if c.breaker != nil {
+				c.breaker.abort(host)
+			}
+			return nil, wrapNoRetryErr(err)
+		}
+	}
+
+	resp, err := c.client.execute(r)
+	if c.breaker != nil {
+		switch {
+		case ctx.Err() != nil:
+			c.breaker.abort(host)
+		case err != nil && isUnavailableStatus(statusOf(resp)):
+			c.breaker.Failure(host)
+		default:
+			c.breaker.Success(host)
+		}
+	}
 	return resp, err
 }
 
+// hostOf returns the host the request is sent to, relative URLs go to the client's endpoint
+func (c *RetryableClient) hostOf(r *Request) string {
+	if u, err := url.Parse(r.URL); err == nil && u.IsAbs() {
+		return u.Host
+	}
+	if u, err := url.Parse(c.client.host); err == nil {
+		return u.Host
+	}
+	return c.client.host
+}
+
 func (c *RetryableClient) log() hclog.Logger {
 	return c.client.logger
 }
@@ -177,6 +235,20 @@ func (c *RetryableClient) WithRetryAfter(retryAfter RetryAfterFunc) *RetryableCl
 	return c
 }
 
+// WithRateLimiter limits the attempts sent to every host, the limiter can be shared by the clients
+// of the same provider
+func (c *RetryableClient) WithRateLimiter(limiter *RateLimiter) *RetryableClient {
+	c.limiter = limiter
+	return c
+}
+
+// WithCircuitBreaker stops sending requests to a host failing repeatedly, the requests fail
+// with ErrCircuitOpen until the breaker half-opens
+func (c *RetryableClient) WithCircuitBreaker(breaker *CircuitBreaker) *RetryableClient {
+	c.breaker = breaker
+	return c
+}
+
 // AddRetryCondition method adds a retry condition function to array of functions
 // that are checked to determine if the request is retried. The request will
 // retry if any of the functions return true and error is nil.
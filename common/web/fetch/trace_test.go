@@ -0,0 +1,101 @@
+package fetch
+
+import (
+	"context"
+	"crypto-trade-client/common/config"
+	"crypto-trade-client/common/web"
+	"net/http"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTraceServer(t *testing.T, failFirst bool) (string, func() []http.Header) {
+	var lock sync.Mutex
+	var headers []http.Header
+	ts := createTestServer(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		headers = append(headers, r.Header.Clone())
+		n := len(headers)
+		lock.Unlock()
+		if failFirst && n == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+	t.Cleanup(ts.Close)
+	return ts.URL, func() []http.Header {
+		lock.Lock()
+		defer lock.Unlock()
+		return headers
+	}
+}
+
+func Test_ClientTraceHeaders(t *testing.T) {
+	traceparent := regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`)
+
+	t.Run("generated", func(t *testing.T) {
+		url, headers := createTraceServer(t, false)
+		req := NewClient(hclog.L()).Get(url)
+		_, err := req.Execute()
+		require.NoError(t, err)
+
+		h := headers()[0]
+		assert.Len(t, req.TraceId(), 32)
+		assert.Equal(t, req.TraceId(), h.Get(web.HdrRequestID))
+		assert.Regexp(t, traceparent, h.Get(web.HdrTraceparent))
+		assert.Contains(t, h.Get(web.HdrTraceparent), req.TraceId())
+	})
+
+	t.Run("from context", func(t *testing.T) {
+		url, headers := createTraceServer(t, false)
+		ctx := web.WithTraceID(context.Background(), "payout-42")
+		_, err := NewClient(hclog.L()).Get(url).WithContext(ctx).Execute()
+		require.NoError(t, err)
+
+		h := headers()[0]
+		assert.Equal(t, "payout-42", h.Get(web.HdrRequestID))
+		// not a W3C trace-id
+		assert.Empty(t, h.Get(web.HdrTraceparent))
+	})
+
+	t.Run("set trace", func(t *testing.T) {
+		url, headers := createTraceServer(t, false)
+		ctx := web.WithTraceID(context.Background(), "payout-42")
+		_, err := NewClient(hclog.L()).Get(url).WithContext(ctx).SetTrace("4bf92f3577b34da6a3ce929d0e0e4736").Execute()
+		require.NoError(t, err)
+
+		h := headers()[0]
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", h.Get(web.HdrRequestID))
+		assert.Regexp(t, `^00-4bf92f3577b34da6a3ce929d0e0e4736-`, h.Get(web.HdrTraceparent))
+	})
+
+	t.Run("same id on retries", func(t *testing.T) {
+		url, headers := createTraceServer(t, true)
+		_, err := NewRetryableClient(NewClient(hclog.L())).WithRetryCount(2).Get(url).Execute()
+		require.NoError(t, err)
+
+		h := headers()
+		require.Len(t, h, 2)
+		assert.NotEmpty(t, h[0].Get(web.HdrRequestID))
+		assert.Equal(t, h[0].Get(web.HdrRequestID), h[1].Get(web.HdrRequestID))
+	})
+}
+
+func Test_BalancedTransportTraceHeaders(t *testing.T) {
+	url, headers := createTraceServer(t, false)
+	b := NewBalancedClient([]config.Endpoint{{URL: url}}, hclog.L())
+
+	req, err := http.NewRequestWithContext(web.WithTraceID(context.Background(), "payout-42"), http.MethodGet, url, nil)
+	require.NoError(t, err)
+	resp, err := b.HTTPClient().Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, "payout-42", headers()[0].Get(web.HdrRequestID))
+}
@@ -25,7 +25,7 @@ func jsonAppErrorReporterT(errType gin.ErrorType) gin.HandlerFunc {
 				parsedError = web.ErrInternal
 			}
 
-			hclog.L().Named("gin-error").Warn(parsedError.Error())
+			web.TraceLogger(c.Request.Context(), hclog.L().Named("gin-error")).Warn(parsedError.Error())
 
 			// Put the error into response
 			c.IndentedJSON(parsedError.Code(), web.NewErrorResponse(parsedError))
@@ -1,12 +1,16 @@
 package middleware
 
 import (
+	"crypto-trade-client/common/web"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/hashicorp/go-hclog"
 	"time"
 )
 
+// HcLogger logs the requests with their trace id. The trace id is taken from the X-Request-Id or traceparent
+// header, or generated, then echoed in the X-Request-Id response header and put in the request context:
+// the outbound fetch and rpc calls sent with c.Request.Context() carry it to the node providers.
 func HcLogger(color bool, logger hclog.Logger) gin.HandlerFunc {
 	logFormatter := func(param gin.LogFormatterParams) string {
 		var statusColor, methodColor, resetColor string
@@ -36,6 +40,13 @@ func HcLogger(color bool, logger hclog.Logger) gin.HandlerFunc {
 		path := c.Request.URL.Path
 		raw := c.Request.URL.RawQuery
 
+		traceID := web.TraceIDFromHeader(c.Request.Header)
+		if traceID == "" {
+			traceID = web.NewTraceID()
+		}
+		c.Request = c.Request.WithContext(web.WithTraceID(c.Request.Context(), traceID))
+		c.Header(web.HdrRequestID, traceID)
+
 		// Process request
 		c.Next()
 
@@ -60,6 +71,6 @@ func HcLogger(color bool, logger hclog.Logger) gin.HandlerFunc {
 		}
 
 		param.Path = path
-		logger.Info(logFormatter(param))
+		logger.Info(logFormatter(param), web.TraceIDLogKey, traceID)
 	}
 }
@@ -0,0 +1,118 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+	"strings"
+)
+
+var (
+	HdrRequestID   = "X-Request-Id"
+	HdrTraceparent = "traceparent"
+
+	// TraceIDLogKey is the key of the trace id in the log lines
+	TraceIDLogKey = "trace_id"
+)
+
+type traceIDKey struct{}
+
+// NewTraceID returns a random trace id, 32 lowercase hex digits as the trace-id of W3C trace context
+func NewTraceID() string {
+	return randomHex(16)
+}
+
+// WithTraceID returns a copy of ctx carrying the trace id, the outbound requests sent with it carry the id too
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFromContext returns the trace id of ctx, empty when it has none
+func TraceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
+
+// TraceIDFromHeader returns the trace id sent in the X-Request-Id header, or else in the traceparent header,
+// empty when there is none
+func TraceIDFromHeader(header http.Header) string {
+	if id := strings.TrimSpace(header.Get(HdrRequestID)); id != "" {
+		return id
+	}
+	// traceparent is version-traceid-parentid-flags
+	parts := strings.Split(header.Get(HdrTraceparent), "-")
+	if len(parts) == 4 && isW3CTraceID(parts[1]) {
+		return parts[1]
+	}
+	return ""
+}
+
+// SetTraceHeaders sets the X-Request-Id header to the trace id, and a traceparent header when the id is a
+// W3C trace-id. Headers already set are kept.
+func SetTraceHeaders(header http.Header, traceID string) {
+	if traceID == "" {
+		return
+	}
+	if header.Get(HdrRequestID) == "" {
+		header.Set(HdrRequestID, traceID)
+	}
+	if header.Get(HdrTraceparent) == "" && isW3CTraceID(traceID) {
+		header.Set(HdrTraceparent, "00-"+traceID+"-"+randomHex(8)+"-01")
+	}
+}
+
+// NewTraceTransport returns a transport setting the trace headers of the requests to the trace id of their context,
+// for the HTTP clients of libraries which send their own requests, e.g. go-ethereum's rpc client.
+// A nil base is http.DefaultTransport.
+func NewTraceTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &traceTransport{base: base}
+}
+
+type traceTransport struct {
+	base http.RoundTripper
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	traceID := TraceIDFromContext(req.Context())
+	if traceID == "" {
+		return t.base.RoundTrip(req)
+	}
+	// a RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	SetTraceHeaders(req.Header, traceID)
+	return t.base.RoundTrip(req)
+}
+
+// TraceLogger returns the logger with the trace id of ctx attached to its lines
+func TraceLogger(ctx context.Context, logger hclog.Logger) hclog.Logger {
+	if traceID := TraceIDFromContext(ctx); traceID != "" {
+		return logger.With(TraceIDLogKey, traceID)
+	}
+	return logger
+}
+
+func isW3CTraceID(id string) bool {
+	if len(id) != 32 || id == strings.Repeat("0", 32) {
+		return false
+	}
+	for _, c := range id {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}